@@ -5,18 +5,19 @@ go 1.22.3
 require (
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go v1.55.5
+	github.com/bogem/id3v2/v2 v2.1.4
 	github.com/gcottom/go-zaplog v0.0.3
 	github.com/gcottom/mp3meta v0.0.0-20240614011545-57dbee245b0d
 	github.com/gcottom/retry v0.1.1
 	github.com/zmb3/spotify/v2 v2.4.2
 	go.uber.org/zap v1.27.0
+	golang.org/x/image v0.18.0
 	golang.org/x/oauth2 v0.22.0
 )
 
 require (
 	github.com/aler9/writerseeker v1.1.0 // indirect
-	github.com/bogem/id3v2/v2 v2.1.4 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
-	golang.org/x/text v0.15.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
 )
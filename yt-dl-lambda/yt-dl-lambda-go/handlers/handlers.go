@@ -6,16 +6,19 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/gcottom/go-zaplog"
 	"github.com/gcottom/retry"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/pkg/coverart"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/pkg/http_client"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/dynamodb"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/s3"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/sqs"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/converter"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/genre"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/meta"
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
 	"go.uber.org/zap"
@@ -74,7 +77,8 @@ func Convert(ctx context.Context, sqsEvent events.SQSEvent) error {
 		defer data.Close()
 		defer os.Remove(data.Name())
 		dynamoClient := dynamodb.CreateDynamoClient(ctx)
-		if err := converter.Convert(id); err != nil {
+		replayGain, err := converter.Convert(id, replayGainFromEnv())
+		if err != nil {
 			zaplog.Error("Failed to convert file", zap.Error(err))
 			if re := dynamoClient.PutTrack(ctx, &dynamodb.DBTrack{ID: id, Status: dynamodb.StatusFailed}); re != nil {
 				return re
@@ -87,6 +91,42 @@ func Convert(ctx context.Context, sqsEvent events.SQSEvent) error {
 		if _, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, sqs.SQSDeleteMessage, sqs.SQSConverterURL, record); err != nil {
 			return err
 		}
+		// a missing fingerprint only means the local service cannot check the track for duplicates
+		fingerprint, err := converter.Fingerprint(id)
+		if err != nil {
+			zaplog.Error("Failed to fingerprint file", zap.Error(err))
+		}
+		track, err := dynamoClient.GetTrackByID(ctx, id)
+		if err == nil && (replayGain != nil || fingerprint != "") {
+			// the meta step tags the file with the gain, the local service reads it back for album gain
+			track.ReplayGain = replayGain
+			track.Fingerprint = fingerprint
+			if err := dynamoClient.PutTrack(ctx, track); err != nil {
+				zaplog.Error("Failed to save analysis", zap.Error(err))
+			}
+		}
+		if err == nil && track.Genre != "" {
+			// a genre given with the download skips the classifier
+			message, err := json.Marshal(sqs.MetaQueueSQSMessage{ID: id, Genre: track.Genre})
+			if err != nil {
+				return err
+			}
+			if _, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, sqs.SQSSendMessage, sqs.SQSMetaURL, string(message)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !genreClassifierFromEnv() {
+			// without the classifier the meta step takes the genres from Spotify
+			message, err := json.Marshal(sqs.MetaQueueSQSMessage{ID: id})
+			if err != nil {
+				return err
+			}
+			if _, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, sqs.SQSSendMessage, sqs.SQSMetaURL, string(message)); err != nil {
+				return err
+			}
+			continue
+		}
 		if _, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, sqs.SQSSendMessage, sqs.SQSGenreURL, id); err != nil {
 			return err
 		}
@@ -104,15 +144,28 @@ func Meta(ctx context.Context, sqsEvent events.SQSEvent) error {
 		zaplog.InfoC(ctx, "Processing record", zap.Any("record", recordData))
 		httpClient := http_client.NewHTTPClient()
 		dynamoClient := dynamodb.CreateDynamoClient(ctx)
+		genrePolicy := genrePolicyFromEnv(ctx)
 		metaService := &meta.Service{HTTPClient: httpClient, DBClient: dynamoClient,
-			SpotifyConfig: &clientcredentials.Config{ClientID: os.Getenv("SPOTIFY_CLIENT_ID"), ClientSecret: os.Getenv("SPOTIFY_CLIENT_SECRET"), TokenURL: spotifyauth.TokenURL}}
+			SpotifyConfig: &clientcredentials.Config{ClientID: os.Getenv("SPOTIFY_CLIENT_ID"), ClientSecret: os.Getenv("SPOTIFY_CLIENT_SECRET"), TokenURL: spotifyauth.TokenURL},
+			CoverOptions:  coverOptionsFromEnv(),
+			GenrePolicy:   genrePolicy}
 		res, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s3.DownloadFromS3Buf, fmt.Sprintf("%s.mp3", recordData.ID), s3.YTDLS3Bucket)
 		if err != nil {
 			zaplog.ErrorC(ctx, "Failed to download file", zap.Error(err))
 			return err
 		}
 		data := res[0].(*aws.WriteAtBuffer)
-		if err := metaService.SaveMeta(ctx, data.Bytes(), recordData.ID, recordData.Genre); err != nil {
+		// no tags means the classifier is disabled or failed, the genres then come from Spotify alone
+		var classified []string
+		var audioTags []dynamodb.TagScore
+		if recordData.Genre != "" {
+			classified = []string{recordData.Genre}
+		} else if len(recordData.Tags) > 0 {
+			classified = genrePolicy.Genres(recordData.Tags)
+			audioTags = genrePolicy.Tags(recordData.Tags)
+			zaplog.InfoC(ctx, "Classified genres", zap.String("id", recordData.ID), zap.Strings("genres", classified), zap.Any("tags", audioTags))
+		}
+		if err := metaService.SaveMeta(ctx, data.Bytes(), recordData.ID, classified, audioTags); err != nil {
 			if re := dynamoClient.PutTrack(ctx, &dynamodb.DBTrack{ID: recordData.ID, Status: dynamodb.StatusFailed}); re != nil {
 				return re
 			}
@@ -204,3 +257,61 @@ func GetPresignedUploadURL(ctx context.Context, req events.APIGatewayProxyReques
 func UnhandledMethod() (*events.APIGatewayProxyResponse, error) {
 	return nil, nil
 }
+
+// replayGainFromEnv tells whether converted tracks are analyzed for ReplayGain, it is on unless REPLAY_GAIN is
+// set to false.
+func replayGainFromEnv() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("REPLAY_GAIN"))
+	return err != nil || enabled
+}
+
+// genreClassifierFromEnv tells whether converted tracks go through the genre classifier, it is on unless
+// GENRE_CLASSIFIER is set to false.
+func genreClassifierFromEnv() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("GENRE_CLASSIFIER"))
+	return err != nil || enabled
+}
+
+// genrePolicyFromEnv reads how classifier tags become genres. GENRE_MODEL_WEIGHTS ("MSD_musicnn=2,MTT_vgg=0.5"),
+// GENRE_WHITELIST ("rock,Hip-Hop"), GENRE_ALIASES ("Hip-Hop=Hip Hop"), GENRE_TOP_N, GENRE_COUNT, GENRE_STRATEGY
+// (classifier, spotify or merge), GENRE_TAG_COUNT and GENRE_MOODS ("chill,sad") are optional, unset or invalid
+// values fall back to the defaults.
+func genrePolicyFromEnv(ctx context.Context) genre.Policy {
+	policy := genre.Policy{Weights: map[string]float64{}, Whitelist: genre.DefaultWhitelist, Aliases: genre.DefaultAliases, Moods: genre.DefaultMoods}
+	switch strategy := os.Getenv("GENRE_STRATEGY"); strategy {
+	case genre.StrategyClassifier, genre.StrategySpotify, genre.StrategyMerge:
+		policy.Strategy = strategy
+	case "":
+		policy.Strategy = genre.StrategyClassifier
+	default:
+		zaplog.ErrorC(ctx, "Invalid GENRE_STRATEGY", zap.String("strategy", strategy))
+		policy.Strategy = genre.StrategyClassifier
+	}
+	if weights, err := genre.ParseWeights(os.Getenv("GENRE_MODEL_WEIGHTS")); err != nil {
+		zaplog.ErrorC(ctx, "Invalid GENRE_MODEL_WEIGHTS", zap.Error(err))
+	} else {
+		policy.Weights = weights
+	}
+	if whitelist := genre.ParseList(os.Getenv("GENRE_WHITELIST")); len(whitelist) > 0 {
+		policy.Whitelist = whitelist
+	}
+	if aliases, err := genre.ParseAliases(os.Getenv("GENRE_ALIASES")); err != nil {
+		zaplog.ErrorC(ctx, "Invalid GENRE_ALIASES", zap.Error(err))
+	} else if len(aliases) > 0 {
+		policy.Aliases = aliases
+	}
+	policy.TopN, _ = strconv.Atoi(os.Getenv("GENRE_TOP_N"))
+	policy.Count, _ = strconv.Atoi(os.Getenv("GENRE_COUNT"))
+	policy.TagCount, _ = strconv.Atoi(os.Getenv("GENRE_TAG_COUNT"))
+	if moods := genre.ParseList(os.Getenv("GENRE_MOODS")); len(moods) > 0 {
+		policy.Moods = moods
+	}
+	return policy
+}
+
+// coverOptionsFromEnv reads the cover art bounds, unset or invalid values fall back to the defaults.
+func coverOptionsFromEnv() coverart.Options {
+	maxSize, _ := strconv.Atoi(os.Getenv("COVER_ART_MAX_SIZE"))
+	maxBytes, _ := strconv.Atoi(os.Getenv("COVER_ART_MAX_BYTES"))
+	return coverart.Options{MaxSize: maxSize, MaxBytes: maxBytes}
+}
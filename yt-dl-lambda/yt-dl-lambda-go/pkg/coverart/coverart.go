@@ -0,0 +1,197 @@
+package coverart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"regexp"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	DefaultMaxSize  = 1200
+	DefaultMaxBytes = 500 * 1024
+
+	// darkLevel is the brightest a pixel of a letterbox bar may be, on the 0 to 255 scale
+	darkLevel = 24
+	// barTolerance is the share of pixels in a bar line allowed to be brighter, JPEG noise and watermarks
+	barTolerance = 0.02
+	// minQuality is the lowest JPEG quality tried before the image is scaled down to fit the byte budget
+	minQuality = 50
+)
+
+var ErrNoImage = errors.New("no cover art could be downloaded")
+
+// Options bound the processed cover. MaxSize is the longest side in pixels and MaxBytes the size of the encoded
+// JPEG, zero values use the defaults.
+type Options struct {
+	MaxSize  int
+	MaxBytes int
+}
+
+var (
+	// ytimgThumbnail matches YouTube video thumbnails, the same image is served in several sizes by name
+	ytimgThumbnail = regexp.MustCompile(`^(https?://i\d?\.ytimg\.com)/vi(?:_webp)?/([^/]+)/[a-z0-9_]+\.(?:jpg|webp)(?:\?.*)?$`)
+	// googleSize matches the size parameters of YouTube Music artwork on googleusercontent, "=w120-h120-l90-rj"
+	googleSize = regexp.MustCompile(`=w\d+-h\d+`)
+)
+
+// ytimgVariants are the YouTube thumbnail names from the largest down, maxresdefault only exists for HD uploads.
+var ytimgVariants = []string{"maxresdefault", "sddefault", "hqdefault", "mqdefault"}
+
+// Variants returns the URLs a cover may be fetched from, the largest variant first and the given URL last.
+func Variants(url string, maxSize int) []string {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	urls := make([]string, 0, len(ytimgVariants)+1)
+	if match := ytimgThumbnail.FindStringSubmatch(url); match != nil {
+		for _, name := range ytimgVariants {
+			urls = append(urls, fmt.Sprintf("%s/vi/%s/%s.jpg", match[1], match[2], name))
+		}
+	} else if googleSize.MatchString(url) {
+		urls = append(urls, googleSize.ReplaceAllString(url, fmt.Sprintf("=w%d-h%d", maxSize, maxSize)))
+	}
+	for _, u := range urls {
+		if u == url {
+			return urls
+		}
+	}
+	return append(urls, url)
+}
+
+// Fetch downloads the largest variant of a cover that the server has.
+func Fetch(ctx context.Context, client *http.Client, url string, maxSize int) ([]byte, error) {
+	var lastErr error
+	for _, variant := range Variants(url, maxSize) {
+		data, err := get(ctx, client, variant)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w: %v", ErrNoImage, lastErr)
+}
+
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Process turns a downloaded cover of any supported format (JPEG, PNG, GIF or WebP) into a square baseline JPEG:
+// letterbox bars are cropped, the rest is cropped to a centered square, scaled down to MaxSize and encoded at the
+// highest quality that fits MaxBytes.
+func Process(data []byte, opts Options) ([]byte, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cover art: %w", err)
+	}
+	img = square(img, trimBars(img))
+	size := min(img.Bounds().Dx(), opts.MaxSize)
+	for {
+		scaled := resize(img, size)
+		for quality := 90; quality >= minQuality; quality -= 10 {
+			buf := new(bytes.Buffer)
+			if err := jpeg.Encode(buf, scaled, &jpeg.Options{Quality: quality}); err != nil {
+				return nil, err
+			}
+			if buf.Len() <= opts.MaxBytes {
+				return buf.Bytes(), nil
+			}
+		}
+		if size <= 100 {
+			return nil, fmt.Errorf("cover art does not fit in %d bytes", opts.MaxBytes)
+		}
+		size = size * 3 / 4
+	}
+}
+
+// trimBars returns the bounds of the image without the dark bars along its edges. Bars are only cropped while
+// at least a quarter of each side is left, a dark cover is not eaten away.
+func trimBars(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	minW, minH := b.Dx()/4, b.Dy()/4
+	for b.Dy() > minH && darkRow(img, b, b.Min.Y) {
+		b.Min.Y++
+	}
+	for b.Dy() > minH && darkRow(img, b, b.Max.Y-1) {
+		b.Max.Y--
+	}
+	for b.Dx() > minW && darkColumn(img, b, b.Min.X) {
+		b.Min.X++
+	}
+	for b.Dx() > minW && darkColumn(img, b, b.Max.X-1) {
+		b.Max.X--
+	}
+	return b
+}
+
+func darkRow(img image.Image, b image.Rectangle, y int) bool {
+	bright := 0
+	for x := b.Min.X; x < b.Max.X; x++ {
+		if !dark(img, x, y) {
+			bright++
+		}
+	}
+	return float64(bright) <= barTolerance*float64(b.Dx())
+}
+
+func darkColumn(img image.Image, b image.Rectangle, x int) bool {
+	bright := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		if !dark(img, x, y) {
+			bright++
+		}
+	}
+	return float64(bright) <= barTolerance*float64(b.Dy())
+}
+
+func dark(img image.Image, x int, y int) bool {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return max(r, g, b)>>8 <= darkLevel
+}
+
+// square crops the centered square out of the given bounds.
+func square(img image.Image, b image.Rectangle) image.Image {
+	side := min(b.Dx(), b.Dy())
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	out := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(out, out.Bounds(), img, image.Pt(x, y), draw.Src)
+	return out
+}
+
+func resize(img image.Image, size int) image.Image {
+	if img.Bounds().Dx() == size {
+		return img
+	}
+	out := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(out, out.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return out
+}
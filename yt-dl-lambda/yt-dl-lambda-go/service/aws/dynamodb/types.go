@@ -24,14 +24,58 @@ type DBNotFoundError struct {
 }
 
 type DBTrack struct {
-	ID          string `dynamodbav:"id" json:"id"`
-	Status      string `dynamodbav:"status" json:"status,omitempty"`
-	URL         string `dynamodbav:"url" json:"url,omitempty"`
-	Title       string `dynamodbav:"title" json:"title"`
-	Artist      string `dynamodbav:"artist" json:"artist"`
-	Album       string `dynamodbav:"album" json:"album,omitempty"`
-	CoverArtURL string `dynamodbav:"cover_art_url" json:"cover_art_url,omitempty"`
-	FileName    string `dynamodbav:"file_name" json:"file_name,omitempty"`
+	ID                        string            `dynamodbav:"id" json:"id"`
+	Status                    string            `dynamodbav:"status" json:"status,omitempty"`
+	URL                       string            `dynamodbav:"url" json:"url,omitempty"`
+	Title                     string            `dynamodbav:"title" json:"title"`
+	Artist                    string            `dynamodbav:"artist" json:"artist"`
+	Artists                   []string          `dynamodbav:"artists" json:"artists,omitempty"`
+	Album                     string            `dynamodbav:"album" json:"album,omitempty"`
+	Genre                     string            `dynamodbav:"genre" json:"genre,omitempty"`
+	Genres                    []string          `dynamodbav:"genres" json:"genres,omitempty"`
+	GenreSources              map[string]string `dynamodbav:"genre_sources" json:"genre_sources,omitempty"`
+	AlbumArtist               string            `dynamodbav:"album_artist" json:"album_artist,omitempty"`
+	TrackNumber               int               `dynamodbav:"track_number" json:"track_number,omitempty"`
+	TrackTotal                int               `dynamodbav:"track_total" json:"track_total,omitempty"`
+	DiscNumber                int               `dynamodbav:"disc_number" json:"disc_number,omitempty"`
+	DiscTotal                 int               `dynamodbav:"disc_total" json:"disc_total,omitempty"`
+	CoverArtURL               string            `dynamodbav:"cover_art_url" json:"cover_art_url,omitempty"`
+	FileName                  string            `dynamodbav:"file_name" json:"file_name,omitempty"`
+	ReleaseDate               string            `dynamodbav:"release_date" json:"release_date,omitempty"`
+	Year                      int               `dynamodbav:"year" json:"year,omitempty"`
+	ISRC                      string            `dynamodbav:"isrc" json:"isrc,omitempty"`
+	Composer                  string            `dynamodbav:"composer" json:"composer,omitempty"`
+	Explicit                  bool              `dynamodbav:"explicit" json:"explicit,omitempty"`
+	DurationMs                int               `dynamodbav:"duration_ms" json:"duration_ms,omitempty"`
+	SpotifyTrackID            string            `dynamodbav:"spotify_track_id" json:"spotify_track_id,omitempty"`
+	SpotifyAlbumID            string            `dynamodbav:"spotify_album_id" json:"spotify_album_id,omitempty"`
+	SpotifyArtistIDs          []string          `dynamodbav:"spotify_artist_ids" json:"spotify_artist_ids,omitempty"`
+	MusicBrainzRecordingID    string            `dynamodbav:"musicbrainz_recording_id" json:"musicbrainz_recording_id,omitempty"`
+	MusicBrainzReleaseID      string            `dynamodbav:"musicbrainz_release_id" json:"musicbrainz_release_id,omitempty"`
+	MusicBrainzReleaseGroupID string            `dynamodbav:"musicbrainz_release_group_id" json:"musicbrainz_release_group_id,omitempty"`
+	MusicBrainzArtistIDs      []string          `dynamodbav:"musicbrainz_artist_ids" json:"musicbrainz_artist_ids,omitempty"`
+	Lyrics                    string            `dynamodbav:"lyrics" json:"lyrics,omitempty"`
+	SyncedLyrics              string            `dynamodbav:"synced_lyrics" json:"synced_lyrics,omitempty"`
+	MatchNote                 string            `dynamodbav:"match_note" json:"match_note,omitempty"`
+	ReplayGain                *ReplayGain       `dynamodbav:"replay_gain" json:"replay_gain,omitempty"`
+	Fingerprint               string            `dynamodbav:"fingerprint" json:"fingerprint,omitempty"`
+	AudioTags                 []TagScore        `dynamodbav:"audio_tags" json:"audio_tags,omitempty"`
+	Moods                     []string          `dynamodbav:"moods" json:"moods,omitempty"`
+	Styles                    []string          `dynamodbav:"styles" json:"styles,omitempty"`
+}
+
+// TagScore is a tag the genre classifier gave a track and its likelihood between 0 and 1, averaged over the models.
+type TagScore struct {
+	Tag   string  `dynamodbav:"tag" json:"tag"`
+	Score float64 `dynamodbav:"score" json:"score"`
+}
+
+// ReplayGain is the loudness of a converted track, measured by the converter. Loudness is the integrated
+// loudness in LUFS, TrackGain the ReplayGain 2.0 gain in dB and TrackPeak the linear true peak.
+type ReplayGain struct {
+	Loudness  float64 `dynamodbav:"loudness" json:"loudness"`
+	TrackGain float64 `dynamodbav:"track_gain" json:"track_gain"`
+	TrackPeak float64 `dynamodbav:"track_peak" json:"track_peak"`
 }
 
 type DynamoClient struct {
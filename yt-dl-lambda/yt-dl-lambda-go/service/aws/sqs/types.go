@@ -27,7 +27,15 @@ func init() {
 	SQSMetaURL = fmt.Sprintf(SQSFmtBaseURL, region, account, SQSMeta)
 }
 
+// MetaQueueSQSMessage asks the meta step to tag a converted track. Genre is set when the genre is already known,
+// otherwise Tags holds the classifier's scores for every tag, keyed by model.
 type MetaQueueSQSMessage struct {
-	ID    string `json:"id"`
-	Genre string `json:"genre"`
+	ID    string                `json:"id"`
+	Genre string                `json:"genre"`
+	Tags  map[string][]TagScore `json:"tags,omitempty"`
+}
+
+type TagScore struct {
+	Tag   string  `json:"tag"`
+	Score float64 `json:"score"`
 }
@@ -2,32 +2,58 @@ package converter
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"os/exec"
 	"path"
+	"regexp"
+	"strconv"
 
 	"github.com/gcottom/go-zaplog"
 	"github.com/gcottom/retry"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/pkg/fingerprint"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/dynamodb"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/s3"
 	"go.uber.org/zap"
 )
 
-func Convert(id string) error {
+// ReplayGainReference is the loudness ReplayGain 2.0 brings every track to, in LUFS.
+const ReplayGainReference = -18.0
+
+var (
+	// the ebur128 filter logs its summary when the stream ends, "I: -14.2 LUFS" and "Peak: -0.3 dBFS"
+	integratedLoudness = regexp.MustCompile(`I:\s+(-?[\d.]+) LUFS`)
+	truePeak           = regexp.MustCompile(`Peak:\s+(-?[\d.]+|-inf) dBFS`)
+)
+
+// Convert encodes the uploaded file to mp3 and uploads it. With analyze set a copy of the audio is measured with
+// the EBU R128 filter while it is encoded and its ReplayGain is returned. The filter resamples what it measures to
+// 48 kHz, so it runs on its own branch of the filter graph and the encoder gets the audio untouched. A failed
+// analysis is logged and returns no ReplayGain, it does not fail the conversion.
+func Convert(id string, analyze bool) (*dynamodb.ReplayGain, error) {
 	// Define input and output paths
 	inputPath := fmt.Sprintf("/tmp/%s.temp", id)
 	outputPath := fmt.Sprintf("/tmp/%s.mp3", id)
 	ffmpegPath := path.Join(os.Getenv("LAMBDA_TASK_ROOT"), "ffmpeg")
 	os.Remove(outputPath)
 	// Define ffmpeg command arguments
-	args := []string{"-i", inputPath, "-c:a", "libmp3lame", "-b:a", "256k", "-f", "mp3", "-"}
+	args := []string{"-i", inputPath}
+	if analyze {
+		// per frame measurements are only logged at the verbose level, the summary is logged when the stream ends
+		args = append(args, "-filter_complex", "[0:a:0]asplit=2[encode][measure];[measure]ebur128=peak=true:framelog=verbose,anullsink", "-map", "[encode]")
+	}
+	args = append(args, "-c:a", "libmp3lame", "-b:a", "256k", "-f", "mp3", "-")
 	cmd := exec.Command(ffmpegPath, args...)
 
 	// Capture stderr to get detailed ffmpeg error messages
 	resultBuffer := bytes.NewBuffer(make([]byte, 0)) // don't preallocate buffer size, it grows automatically and preallocation corrupts the output
 
-	cmd.Stderr = os.Stderr    // bind log stream to stderr
-	cmd.Stdout = resultBuffer // stdout result will be written here
+	logBuffer := new(bytes.Buffer)
+	cmd.Stderr = io.MultiWriter(os.Stderr, logBuffer) // bind log stream to stderr, kept for the loudness summary
+	cmd.Stdout = resultBuffer                         // stdout result will be written here
 
 	// Log the start of the conversion
 	zaplog.Info("converting file", zap.String("id", id))
@@ -35,13 +61,13 @@ func Convert(id string) error {
 	// Start the ffmpeg command
 	if err := cmd.Start(); err != nil {
 		zaplog.Error("Failed to start ffmpeg", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	// Wait for the ffmpeg command to finish
 	if err := cmd.Wait(); err != nil {
 		zaplog.Error("FFmpeg failed", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	// Clean up the output file after processing
@@ -51,8 +77,66 @@ func Convert(id string) error {
 	if _, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s3.UploadToS3,
 		resultBuffer, fmt.Sprintf("%s.mp3", id), s3.YTDLS3Bucket); err != nil {
 		zaplog.Error("Failed to upload to S3", zap.Error(err))
-		return err
+		return nil, err
+	}
+
+	if !analyze {
+		return nil, nil
+	}
+	replayGain, err := parseReplayGain(logBuffer.String())
+	if err != nil {
+		zaplog.Error("Failed to analyze loudness", zap.String("id", id), zap.Error(err))
+		return nil, nil
+	}
+	return replayGain, nil
+}
+
+// parseReplayGain reads the integrated loudness and true peak from the ebur128 summary. ReplayGain 2.0 gains
+// are the distance to the -18 LUFS reference and peaks are linear, 1.0 being full scale.
+func parseReplayGain(log string) (*dynamodb.ReplayGain, error) {
+	loudness := integratedLoudness.FindAllStringSubmatch(log, -1)
+	peak := truePeak.FindAllStringSubmatch(log, -1)
+	if loudness == nil || peak == nil {
+		return nil, fmt.Errorf("no loudness summary in ffmpeg output")
 	}
+	// the summary comes after the running values, so the last match is the whole track
+	integrated, err := strconv.ParseFloat(loudness[len(loudness)-1][1], 64)
+	if err != nil {
+		return nil, err
+	}
+	peakDB := math.Inf(-1)
+	if value := peak[len(peak)-1][1]; value != "-inf" {
+		if peakDB, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, err
+		}
+	}
+	return &dynamodb.ReplayGain{
+		Loudness:  integrated,
+		TrackGain: ReplayGainReference - integrated,
+		TrackPeak: math.Pow(10, peakDB/20),
+	}, nil
+}
+
+// Fingerprint decodes the start of the uploaded file to mono PCM and returns its acoustic fingerprint, the local
+// service compares it with the library to find the same song uploaded under another video.
+func Fingerprint(id string) (string, error) {
+	inputPath := fmt.Sprintf("/tmp/%s.temp", id)
+	ffmpegPath := path.Join(os.Getenv("LAMBDA_TASK_ROOT"), "ffmpeg")
+	args := []string{"-i", inputPath, "-t", strconv.Itoa(fingerprint.MaxSeconds),
+		"-ac", "1", "-ar", strconv.Itoa(fingerprint.SampleRate), "-f", "s16le", "-"}
+	cmd := exec.Command(ffmpegPath, args...)
+	resultBuffer := new(bytes.Buffer)
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = resultBuffer
 
-	return nil
+	zaplog.Info("fingerprinting file", zap.String("id", id))
+	if err := cmd.Run(); err != nil {
+		zaplog.Error("FFmpeg failed", zap.Error(err))
+		return "", err
+	}
+	samples := make([]int16, resultBuffer.Len()/2)
+	if err := binary.Read(resultBuffer, binary.LittleEndian, samples); err != nil {
+		return "", err
+	}
+	return fingerprint.Compute(samples).String(), nil
 }
@@ -0,0 +1,301 @@
+package genre
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/dynamodb"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/sqs"
+)
+
+const (
+	// DefaultTopN is how many of each model's best tags take part in the vote
+	DefaultTopN = 5
+	// DefaultCount is how many genres are written
+	DefaultCount = 1
+	// DefaultTagCount is how many of the best tags are kept as the track's moods and styles
+	DefaultTagCount = 10
+)
+
+// Strategies for combining the classifier's genres with the genres Spotify lists for the track's artists.
+const (
+	StrategyClassifier = "classifier"
+	StrategySpotify    = "spotify"
+	StrategyMerge      = "merge"
+)
+
+// Sources a written genre can come from.
+const (
+	SourceRequest    = "request"
+	SourceClassifier = "classifier"
+	SourceSpotify    = "spotify"
+)
+
+// DefaultWhitelist are the tags of the musicnn models that name a genre, in no particular order. Instruments,
+// moods and decades are kept since the models often only agree on those.
+var DefaultWhitelist = []string{
+	"classical", "techno", "strings", "drums", "electronic", "rock", "piano", "ambient", "violin", "vocal", "synth",
+	"indian", "opera", "harpsichord", "flute", "pop", "sitar", "classic", "choir", "new age", "dance", "harp",
+	"cello", "country", "metal", "choral", "alternative", "indie", "00s", "alternative rock", "jazz", "chillout",
+	"classic rock", "soul", "indie rock", "Mellow", "electronica", "80s", "folk", "90s", "chill", "instrumental",
+	"punk", "oldies", "blues", "hard rock", "acoustic", "experimental", "Hip-Hop", "70s", "party", "easy listening",
+	"funk", "electro", "heavy metal", "Progressive rock", "60s", "rnb", "indie pop", "sad", "House",
+}
+
+// DefaultMoods are the tags of the musicnn models that describe how a track feels, the other tags kept from a
+// track are its styles: genres, instruments, vocals and decades.
+var DefaultMoods = []string{
+	"beautiful", "chill", "chillout", "Mellow", "party", "easy listening", "sexy", "catchy", "sad", "happy", "slow",
+	"fast", "loud", "quiet", "soft", "weird",
+}
+
+// DefaultAliases rename tags whose spelling is not the usual genre name.
+var DefaultAliases = map[string]string{
+	"Hip-Hop": "Hip Hop",
+	"rnb":     "R&B",
+}
+
+// Policy turns the classifier's tag scores into genres. Every model votes for its TopN best tags, the best one
+// getting TopN points times the model's weight and the next one point less. The Count best voted tags on the
+// whitelist are written, renamed by the aliases, or the best tag overall when none is on the whitelist. No genre
+// is written when every vote came from models weighted 0. Whitelist and aliases match tags case-insensitively.
+// Strategy tells how they are combined with Spotify's genres, the classifier is preferred when it is empty. The
+// TagCount best tags overall are kept with their scores, split into the tags on the Moods list and the styles.
+type Policy struct {
+	Weights   map[string]float64
+	Whitelist []string
+	Aliases   map[string]string
+	TopN      int
+	Count     int
+	Strategy  string
+	TagCount  int
+	Moods     []string
+}
+
+// Genres picks the genres of a track from the ranked tag scores of each model.
+func (p Policy) Genres(tags map[string][]sqs.TagScore) []string {
+	topN := p.TopN
+	if topN <= 0 {
+		topN = DefaultTopN
+	}
+	votes := make(map[string]float64)
+	order := make([]string, 0)
+	for model, scores := range tags {
+		weight, ok := p.Weights[model]
+		if !ok {
+			weight = 1
+		}
+		scores = append([]sqs.TagScore(nil), scores...)
+		sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+		for rank, score := range scores[:min(len(scores), topN)] {
+			if _, ok := votes[score.Tag]; !ok {
+				order = append(order, score.Tag)
+			}
+			votes[score.Tag] += float64(topN-rank) * weight
+		}
+	}
+	// ties keep a stable order so the same scores always give the same genres
+	sort.Strings(order)
+	sort.SliceStable(order, func(i, j int) bool { return votes[order[i]] > votes[order[j]] })
+
+	whitelist := make(map[string]bool, len(p.Whitelist))
+	for _, tag := range p.Whitelist {
+		whitelist[strings.ToLower(tag)] = true
+	}
+	count := p.Count
+	if count <= 0 {
+		count = DefaultCount
+	}
+	genres := make([]string, 0, count)
+	for _, tag := range order {
+		if len(genres) == count {
+			break
+		}
+		if whitelist[strings.ToLower(tag)] && votes[tag] > 0 {
+			genres = appendUnique(genres, p.name(tag))
+		}
+	}
+	// a tag only voted for by models weighted 0 is not a genre
+	if len(genres) == 0 && len(order) > 0 && votes[order[0]] > 0 {
+		genres = append(genres, p.name(order[0]))
+	}
+	return genres
+}
+
+// Tags ranks every tag by its score averaged over the models that have it, weighted like the vote, and returns
+// the TagCount best renamed by the aliases. Models with a weight of 0 are left out.
+func (p Policy) Tags(tags map[string][]sqs.TagScore) []dynamodb.TagScore {
+	sums := make(map[string]float64)
+	weights := make(map[string]float64)
+	names := make(map[string]string)
+	for model, scores := range tags {
+		weight, ok := p.Weights[model]
+		if !ok {
+			weight = 1
+		}
+		if weight <= 0 {
+			continue
+		}
+		for _, score := range scores {
+			name := p.name(score.Tag)
+			key := strings.ToLower(name)
+			if _, ok := names[key]; !ok {
+				names[key] = name
+			}
+			sums[key] += score.Score * weight
+			weights[key] += weight
+		}
+	}
+	ranked := make([]dynamodb.TagScore, 0, len(names))
+	for key, name := range names {
+		ranked = append(ranked, dynamodb.TagScore{Tag: name, Score: sums[key] / weights[key]})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Tag < ranked[j].Tag
+	})
+	count := p.TagCount
+	if count <= 0 {
+		count = DefaultTagCount
+	}
+	return ranked[:min(len(ranked), count)]
+}
+
+// MoodsAndStyles splits ranked tags into the ones on the Moods list and the rest, keeping their order.
+func (p Policy) MoodsAndStyles(tags []dynamodb.TagScore) ([]string, []string) {
+	moods := make(map[string]bool, len(p.Moods))
+	for _, mood := range p.Moods {
+		moods[strings.ToLower(p.name(mood))] = true
+	}
+	moodTags, styleTags := make([]string, 0), make([]string, 0)
+	for _, tag := range tags {
+		if moods[strings.ToLower(tag.Tag)] {
+			moodTags = append(moodTags, tag.Tag)
+		} else {
+			styleTags = append(styleTags, tag.Tag)
+		}
+	}
+	return moodTags, styleTags
+}
+
+// NeedsSpotify reports whether Combine would use Spotify's genres alongside the given classifier genres, so they
+// are only fetched when they can make a difference.
+func (p Policy) NeedsSpotify(classified []string) bool {
+	return p.Strategy == StrategySpotify || p.Strategy == StrategyMerge || len(classified) == 0
+}
+
+// Combine picks the genres to write from the classifier's and Spotify's and returns the source of each. The
+// preferred source is used when it has any genres and the other one otherwise, merge writes up to Count genres
+// of each, the classifier's first. Spotify's genres are renamed by the aliases but not held to the whitelist,
+// which only lists classifier tags.
+func (p Policy) Combine(classified []string, spotify []string) ([]string, map[string]string) {
+	count := p.Count
+	if count <= 0 {
+		count = DefaultCount
+	}
+	named := make([]string, 0, count)
+	for _, tag := range spotify {
+		if len(named) == count {
+			break
+		}
+		named = appendUnique(named, p.name(tag))
+	}
+	spotify = named
+
+	genres := make([]string, 0, 2*count)
+	sources := make(map[string]string)
+	add := func(values []string, source string) {
+		for _, value := range values {
+			if _, ok := sources[value]; !ok {
+				genres = append(genres, value)
+				sources[value] = source
+			}
+		}
+	}
+	switch {
+	case p.Strategy == StrategyMerge:
+		add(classified, SourceClassifier)
+		add(spotify, SourceSpotify)
+	case p.Strategy == StrategySpotify && len(spotify) > 0, len(classified) == 0:
+		add(spotify, SourceSpotify)
+	default:
+		add(classified, SourceClassifier)
+	}
+	return genres, sources
+}
+
+// name returns the alias of a tag, or the tag in title case.
+func (p Policy) name(tag string) string {
+	for from, to := range p.Aliases {
+		if strings.EqualFold(from, tag) {
+			return to
+		}
+	}
+	return titleCase(tag)
+}
+
+// titleCase capitalises the first letter of every word, "hard rock" becomes "Hard Rock" and "00s" is unchanged.
+func titleCase(tag string) string {
+	out := []rune(tag)
+	for i, r := range out {
+		if i == 0 || out[i-1] == ' ' || out[i-1] == '-' {
+			out[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(out)
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// ParseList reads a comma separated list, "rock,hard rock,Hip-Hop".
+func ParseList(str string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(str, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// ParseWeights reads comma separated model weights, "MSD_musicnn=2,MTT_vgg=0.5".
+func ParseWeights(str string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, item := range ParseList(str) {
+		model, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid model weight %q, expected model=weight", item)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid model weight %q", item)
+		}
+		weights[strings.TrimSpace(model)] = weight
+	}
+	return weights, nil
+}
+
+// ParseAliases reads comma separated tag renames, "Hip-Hop=Hip Hop,rnb=R&B".
+func ParseAliases(str string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, item := range ParseList(str) {
+		from, to, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			return nil, fmt.Errorf("invalid genre alias %q, expected tag=genre", item)
+		}
+		aliases[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+	return aliases, nil
+}
@@ -0,0 +1,228 @@
+package genre
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/dynamodb"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/sqs"
+)
+
+// ranked gives tags falling scores in the order they are listed, like a model's top tags.
+func ranked(tags ...string) []sqs.TagScore {
+	scores := make([]sqs.TagScore, 0, len(tags))
+	for i, tag := range tags {
+		scores = append(scores, sqs.TagScore{Tag: tag, Score: 0.9 - float64(i)*0.1})
+	}
+	return scores
+}
+
+func TestGenres(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		tags   map[string][]sqs.TagScore
+		want   []string
+	}{
+		{"agreeing models", Policy{Whitelist: DefaultWhitelist},
+			map[string][]sqs.TagScore{
+				"MSD_musicnn": ranked("rock", "guitar", "pop"),
+				"MSD_vgg":     ranked("rock", "pop", "guitar"),
+			}, []string{"Rock"}},
+		{"best tag not on the whitelist", Policy{Whitelist: DefaultWhitelist},
+			map[string][]sqs.TagScore{"MSD_musicnn": ranked("guitar", "rock")}, []string{"Rock"}},
+		{"no tag on the whitelist", Policy{Whitelist: DefaultWhitelist},
+			map[string][]sqs.TagScore{"MSD_musicnn": ranked("guitar", "male vocals")}, []string{"Guitar"}},
+		{"tie", Policy{Whitelist: DefaultWhitelist},
+			map[string][]sqs.TagScore{
+				"MSD_musicnn": ranked("rock", "pop"),
+				"MSD_vgg":     ranked("pop", "rock"),
+			}, []string{"Pop"}},
+		{"weights", Policy{Whitelist: DefaultWhitelist, Weights: map[string]float64{"MSD_musicnn": 2}},
+			map[string][]sqs.TagScore{
+				"MSD_musicnn": ranked("rock", "pop"),
+				"MSD_vgg":     ranked("pop", "rock"),
+			}, []string{"Rock"}},
+		{"model weighted 0", Policy{Whitelist: DefaultWhitelist, Weights: map[string]float64{"MSD_musicnn": 0}},
+			map[string][]sqs.TagScore{
+				"MSD_musicnn": ranked("jazz"),
+				"MSD_vgg":     ranked("rock", "jazz"),
+			}, []string{"Rock"}},
+		{"every model weighted 0", Policy{Whitelist: DefaultWhitelist, Weights: map[string]float64{"MSD_musicnn": 0, "MSD_vgg": 0}},
+			map[string][]sqs.TagScore{
+				"MSD_musicnn": ranked("guitar"),
+				"MSD_vgg":     ranked("rock"),
+			}, []string{}},
+		{"no scores", Policy{Whitelist: DefaultWhitelist}, map[string][]sqs.TagScore{}, []string{}},
+		{"top n", Policy{Whitelist: DefaultWhitelist, TopN: 1},
+			map[string][]sqs.TagScore{
+				"MSD_musicnn": ranked("guitar", "rock"),
+				"MSD_vgg":     ranked("guitar", "rock"),
+			}, []string{"Guitar"}},
+		{"count", Policy{Whitelist: DefaultWhitelist, Count: 2},
+			map[string][]sqs.TagScore{"MSD_musicnn": ranked("rock", "guitar", "pop", "jazz")}, []string{"Rock", "Pop"}},
+		{"whitelist case", Policy{Whitelist: []string{"ROCK"}},
+			map[string][]sqs.TagScore{"MSD_musicnn": ranked("guitar", "rock")}, []string{"Rock"}},
+		{"alias case", Policy{Whitelist: DefaultWhitelist, Aliases: DefaultAliases},
+			map[string][]sqs.TagScore{"MSD_musicnn": ranked("hip-hop", "RNB")}, []string{"Hip Hop"}},
+		{"aliases and count", Policy{Whitelist: DefaultWhitelist, Aliases: DefaultAliases, Count: 3},
+			map[string][]sqs.TagScore{"MSD_musicnn": ranked("Hip-Hop", "rnb", "hard rock")}, []string{"Hip Hop", "R&B", "Hard Rock"}},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Genres(tt.tags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Genres() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// appVote is the vote of the Python classifier this package replaced: the top 5 tags of the four models in turn
+// get 5 to 1 points, and the best voted tag on the whitelist, or the best one overall, is the genre.
+func appVote(models [][]string) string {
+	votes := make(map[string]int)
+	order := make([]string, 0)
+	for _, tags := range models {
+		for rank, tag := range tags[:min(len(tags), 5)] {
+			if _, ok := votes[tag]; !ok {
+				order = append(order, tag)
+			}
+			votes[tag] += 5 - rank
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return votes[order[i]] > votes[order[j]] })
+	for _, tag := range order {
+		for _, genre := range DefaultWhitelist {
+			if tag == genre {
+				return tag
+			}
+		}
+	}
+	return order[0]
+}
+
+func TestGenresMatchesAppVote(t *testing.T) {
+	models := []string{"MSD_musicnn", "MSD_vgg", "MTT_musicnn", "MTT_vgg"}
+	tests := [][][]string{
+		{
+			{"rock", "guitar", "classic rock", "hard rock", "alternative"},
+			{"rock", "hard rock", "guitar", "metal", "alternative"},
+			{"guitar", "rock", "loud", "drums", "male vocal"},
+			{"guitar", "rock", "drums", "loud", "fast"},
+		},
+		{
+			{"electronic", "dance", "House", "techno", "electronica"},
+			{"dance", "electronic", "House", "pop", "party"},
+			{"techno", "electronic", "beat", "synth", "fast"},
+			{"techno", "beat", "electronic", "dance", "drums"},
+		},
+		{
+			{"guitar", "male vocals", "loud", "beat", "fast"},
+			{"male vocals", "guitar", "beat", "loud", "slow"},
+			{"guitar", "beat", "loud", "male vocal", "fast"},
+			{"guitar", "loud", "beat", "male vocal", "slow"},
+		},
+		{
+			{"Hip-Hop", "rnb", "soul", "beat", "party"},
+			{"rnb", "Hip-Hop", "soul", "sexy", "beat"},
+			{"beat", "rap", "hip hop", "male vocal", "drums"},
+			{"beat", "rap", "drums", "male vocal", "hip hop"},
+		},
+		{
+			{"classical", "strings", "violin", "cello", "piano"},
+			{"classical", "piano", "strings", "violin", "harpsichord"},
+			{"classical", "strings", "violin", "slow", "soft"},
+			{"classical", "violin", "strings", "cello", "quiet"},
+		},
+	}
+	for _, tt := range tests {
+		tags := make(map[string][]sqs.TagScore, len(models))
+		for i, model := range models {
+			tags[model] = ranked(tt[i]...)
+		}
+		want := appVote(tt)
+		got := Policy{Whitelist: DefaultWhitelist}.Genres(tags)
+		if len(got) != 1 || !strings.EqualFold(got[0], want) {
+			t.Errorf("Genres(%q) = %q, want %q", tt, got, want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	tags := map[string][]sqs.TagScore{
+		"MSD_musicnn": {{Tag: "rock", Score: 0.8}, {Tag: "pop", Score: 0.2}, {Tag: "rnb", Score: 0.1}},
+		"MSD_vgg":     {{Tag: "Pop", Score: 0.6}, {Tag: "rock", Score: 0.2}},
+	}
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []dynamodb.TagScore
+	}{
+		{"average", Policy{Aliases: DefaultAliases},
+			[]dynamodb.TagScore{{Tag: "Rock", Score: 0.5}, {Tag: "Pop", Score: 0.4}, {Tag: "R&B", Score: 0.1}}},
+		{"weights", Policy{Aliases: DefaultAliases, Weights: map[string]float64{"MSD_vgg": 3}},
+			[]dynamodb.TagScore{{Tag: "Pop", Score: 0.5}, {Tag: "Rock", Score: 0.35}, {Tag: "R&B", Score: 0.1}}},
+		{"model weighted 0", Policy{Aliases: DefaultAliases, Weights: map[string]float64{"MSD_vgg": 0}},
+			[]dynamodb.TagScore{{Tag: "Rock", Score: 0.8}, {Tag: "Pop", Score: 0.2}, {Tag: "R&B", Score: 0.1}}},
+		{"tag count", Policy{Aliases: DefaultAliases, TagCount: 2},
+			[]dynamodb.TagScore{{Tag: "Rock", Score: 0.5}, {Tag: "Pop", Score: 0.4}}},
+	}
+	for _, tt := range tests {
+		got := tt.policy.Tags(tags)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Tags() = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Tag != tt.want[i].Tag || math.Abs(got[i].Score-tt.want[i].Score) > 1e-9 {
+				t.Errorf("%s: Tags() = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMoodsAndStyles(t *testing.T) {
+	policy := Policy{Moods: DefaultMoods, Aliases: DefaultAliases}
+	tags := []dynamodb.TagScore{{Tag: "Chill"}, {Tag: "Rock"}, {Tag: "mellow"}, {Tag: "Hip Hop"}, {Tag: "Sad"}}
+	moods, styles := policy.MoodsAndStyles(tags)
+	if want := []string{"Chill", "mellow", "Sad"}; !reflect.DeepEqual(moods, want) {
+		t.Errorf("moods = %q, want %q", moods, want)
+	}
+	if want := []string{"Rock", "Hip Hop"}; !reflect.DeepEqual(styles, want) {
+		t.Errorf("styles = %q, want %q", styles, want)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     Policy
+		classified []string
+		spotify    []string
+		want       []string
+		sources    map[string]string
+	}{
+		{"classifier", Policy{Strategy: StrategyClassifier}, []string{"Rock"}, []string{"indie pop"},
+			[]string{"Rock"}, map[string]string{"Rock": SourceClassifier}},
+		{"classifier by default", Policy{}, []string{"Rock"}, []string{"indie pop"},
+			[]string{"Rock"}, map[string]string{"Rock": SourceClassifier}},
+		{"classifier without genres", Policy{Aliases: DefaultAliases}, []string{}, []string{"hip-hop", "rap"},
+			[]string{"Hip Hop"}, map[string]string{"Hip Hop": SourceSpotify}},
+		{"spotify", Policy{Strategy: StrategySpotify}, []string{"Rock"}, []string{"indie pop", "pop"},
+			[]string{"Indie Pop"}, map[string]string{"Indie Pop": SourceSpotify}},
+		{"spotify without genres", Policy{Strategy: StrategySpotify}, []string{"Rock"}, []string{},
+			[]string{"Rock"}, map[string]string{"Rock": SourceClassifier}},
+		{"merge", Policy{Strategy: StrategyMerge, Count: 2}, []string{"Rock", "Pop"}, []string{"pop", "dance pop", "electropop"},
+			[]string{"Rock", "Pop", "Dance Pop"}, map[string]string{"Rock": SourceClassifier, "Pop": SourceClassifier, "Dance Pop": SourceSpotify}},
+		{"merge without classifier genres", Policy{Strategy: StrategyMerge}, []string{}, []string{"pop"},
+			[]string{"Pop"}, map[string]string{"Pop": SourceSpotify}},
+	}
+	for _, tt := range tests {
+		got, sources := tt.policy.Combine(tt.classified, tt.spotify)
+		if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(sources, tt.sources) {
+			t.Errorf("%s: Combine() = %q, %v, want %q, %v", tt.name, got, sources, tt.want, tt.sources)
+		}
+	}
+}
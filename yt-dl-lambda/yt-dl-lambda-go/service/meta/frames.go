@@ -0,0 +1,169 @@
+package meta
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/dynamodb"
+)
+
+// EmbedExtraFrames writes the frames mp3meta has no setters for into the ID3 tag of an encoded mp3, the audio is
+// copied untouched. It writes:
+//   - the lyrics
+//   - track and disc numbers, without a total when it is unknown
+//   - every genre, mood and style
+//   - the explicit flag
+//   - every credited artist
+//   - the track's ReplayGain
+//   - the Spotify and MusicBrainz IDs
+//   - the processed cover, which mp3meta would re-encode
+func EmbedExtraFrames(data []byte, track *dynamodb.DBTrack, cover []byte) ([]byte, error) {
+	tag, err := id3v2.ParseReader(bytes.NewReader(data), id3v2.Options{Parse: true})
+	if err != nil {
+		return nil, err
+	}
+	addLyricsFrames(tag, track)
+	// mp3meta always writes "n/total", Spotify matches have no totals and would read as "7/0"
+	setTextFrame(tag, "Track number/Position in set", position(track.TrackNumber, track.TrackTotal))
+	setTextFrame(tag, "Part of a set", position(track.DiscNumber, track.DiscTotal))
+	if len(track.Genres) > 1 {
+		tag.SetGenre(joinValues(tag, track.Genres))
+	}
+	// MOOD and STYLE are the frames Picard and MusicBee read, the comment shows every tag with its score
+	setUserText(tag, "MOOD", joinValues(tag, track.Moods))
+	setUserText(tag, "STYLE", joinValues(tag, track.Styles))
+	setComment(tag, tagScoresComment(track.AudioTags))
+	if track.Explicit {
+		// iTunes reads the content advisory from this frame, 1 means explicit
+		setUserText(tag, "ITUNESADVISORY", "1")
+	}
+	// the artist frame holds the display credit, players that read multi-valued tags take the artists from here
+	setUserText(tag, "ARTISTS", joinValues(tag, track.Artists))
+	if track.ReplayGain != nil {
+		// foobar2000 formatting, which is what most players parse
+		setUserText(tag, "REPLAYGAIN_TRACK_GAIN", fmt.Sprintf("%.2f dB", track.ReplayGain.TrackGain))
+		setUserText(tag, "REPLAYGAIN_TRACK_PEAK", fmt.Sprintf("%.6f", track.ReplayGain.TrackPeak))
+	}
+	setUserText(tag, "SPOTIFY_TRACK_ID", track.SpotifyTrackID)
+	setUserText(tag, "SPOTIFY_ALBUM_ID", track.SpotifyAlbumID)
+	setUserText(tag, "SPOTIFY_ARTIST_ID", strings.Join(track.SpotifyArtistIDs, "/"))
+	// MusicBrainz IDs use the frame names Picard writes so other taggers recognise them
+	setUserText(tag, "MusicBrainz Album Id", track.MusicBrainzReleaseID)
+	setUserText(tag, "MusicBrainz Release Group Id", track.MusicBrainzReleaseGroupID)
+	setUserText(tag, "MusicBrainz Artist Id", strings.Join(track.MusicBrainzArtistIDs, "/"))
+	tag.DeleteFrames(tag.CommonID("Unique file identifier"))
+	if track.MusicBrainzRecordingID != "" {
+		tag.AddUFIDFrame(id3v2.UFIDFrame{OwnerIdentifier: "http://musicbrainz.org", Identifier: []byte(track.MusicBrainzRecordingID)})
+	}
+	if cover != nil {
+		tag.DeleteFrames(tag.CommonID("Attached picture"))
+		tag.AddAttachedPicture(id3v2.PictureFrame{
+			Encoding:    id3v2.EncodingUTF8,
+			MimeType:    "image/jpeg",
+			PictureType: id3v2.PTFrontCover,
+			Description: "Front cover",
+			Picture:     cover,
+		})
+	}
+	output := new(bytes.Buffer)
+	if _, err := tag.WriteTo(output); err != nil {
+		return nil, err
+	}
+	output.Write(data[id3TagSize(data):])
+	return output.Bytes(), nil
+}
+
+// setTextFrame replaces the text frame with the given name, an empty value only removes it.
+func setTextFrame(tag *id3v2.Tag, name string, value string) {
+	tag.DeleteFrames(tag.CommonID(name))
+	if value != "" {
+		tag.AddTextFrame(tag.CommonID(name), id3v2.EncodingUTF8, value)
+	}
+}
+
+// position formats a track or disc number, "7/12", or "7" when the total is unknown.
+func position(n int, total int) string {
+	if n <= 0 {
+		return ""
+	}
+	if total <= 0 {
+		return strconv.Itoa(n)
+	}
+	return fmt.Sprintf("%d/%d", n, total)
+}
+
+// setUserText replaces the TXXX frame with the given description, an empty value only removes it.
+func setUserText(tag *id3v2.Tag, description string, value string) {
+	frames := tag.GetFrames(tag.CommonID("User defined text information frame"))
+	tag.DeleteFrames(tag.CommonID("User defined text information frame"))
+	for _, frame := range frames {
+		if udtf, ok := frame.(id3v2.UserDefinedTextFrame); ok && udtf.Description != description {
+			tag.AddUserDefinedTextFrame(udtf)
+		}
+	}
+	if value == "" {
+		return
+	}
+	tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
+		Encoding:    id3v2.EncodingUTF8,
+		Description: description,
+		Value:       value,
+	})
+}
+
+// setComment replaces the comment without a description, an empty text only removes it.
+func setComment(tag *id3v2.Tag, text string) {
+	frames := tag.GetFrames(tag.CommonID("Comments"))
+	tag.DeleteFrames(tag.CommonID("Comments"))
+	for _, frame := range frames {
+		if comment, ok := frame.(id3v2.CommentFrame); ok && comment.Description != "" {
+			tag.AddCommentFrame(comment)
+		}
+	}
+	if text == "" {
+		return
+	}
+	tag.AddCommentFrame(id3v2.CommentFrame{
+		Encoding: id3v2.EncodingUTF8,
+		Language: "eng",
+		Text:     text,
+	})
+}
+
+// tagScoresComment lists tags with their scores, "Chill 0.62, Piano 0.41".
+func tagScoresComment(tags []dynamodb.TagScore) string {
+	parts := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		parts = append(parts, fmt.Sprintf("%s %.2f", tag.Tag, tag.Score))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// valueSeparator separates the values of a multi-valued frame in ID3v2.3, which has no separator of its own. The
+// "/" some taggers use there splits names like "AC/DC".
+const valueSeparator = "; "
+
+// joinValues joins the values of a multi-valued frame, ID3v2.4 separates them with a null byte and ID3v2.3 with
+// valueSeparator.
+func joinValues(tag *id3v2.Tag, values []string) string {
+	if tag.Version() == 4 {
+		return strings.Join(values, "\x00")
+	}
+	return strings.Join(values, valueSeparator)
+}
+
+// id3TagSize returns the length of the ID3v2 tag at the start of data, including its header and footer.
+func id3TagSize(data []byte) int {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return 0
+	}
+	size := int(data[6]&0x7f)<<21 | int(data[7]&0x7f)<<14 | int(data[8]&0x7f)<<7 | int(data[9]&0x7f)
+	size += 10
+	if data[5]&0x10 != 0 {
+		size += 10
+	}
+	return min(size, len(data))
+}
@@ -0,0 +1,49 @@
+package meta
+
+import (
+	"context"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/dynamodb"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/genre"
+	"github.com/zmb3/spotify/v2"
+	"go.uber.org/zap"
+)
+
+// chooseGenres returns the genres to tag a track with and where each came from. A genre given with the download
+// is kept as it is, otherwise the classifier's genres are combined with Spotify's under the genre strategy.
+func (s *Service) chooseGenres(ctx context.Context, track *dynamodb.DBTrack, classified []string) ([]string, map[string]string) {
+	// a genre chosen on an earlier attempt has a source recorded and is picked again
+	if track.Genre != "" && (track.GenreSources == nil || track.GenreSources[track.Genre] == genre.SourceRequest) {
+		return []string{track.Genre}, map[string]string{track.Genre: genre.SourceRequest}
+	}
+	var spotifyGenres []string
+	if s.GenrePolicy.NeedsSpotify(classified) && len(track.SpotifyArtistIDs) > 0 {
+		var err error
+		if spotifyGenres, err = s.SpotifyArtistGenres(ctx, track.SpotifyArtistIDs); err != nil {
+			// the classifier's genres, if any, are still written
+			zaplog.ErrorC(ctx, "failed to get spotify artist genres", zap.String("id", track.ID), zap.Error(err))
+		}
+	}
+	return s.GenrePolicy.Combine(classified, spotifyGenres)
+}
+
+// SpotifyArtistGenres returns the genres Spotify lists for the given artists, the first artist's first.
+func (s *Service) SpotifyArtistGenres(ctx context.Context, artistIDs []string) ([]string, error) {
+	client := spotify.New(s.SpotifyConfig.Client(ctx))
+	ids := make([]spotify.ID, 0, len(artistIDs))
+	for _, id := range artistIDs {
+		ids = append(ids, spotify.ID(id))
+	}
+	artists, err := client.GetArtists(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	genres := make([]string, 0)
+	for _, artist := range artists {
+		if artist != nil {
+			genres = append(genres, artist.Genres...)
+		}
+	}
+	return genres, nil
+}
@@ -0,0 +1,84 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/dynamodb"
+)
+
+// LyricLine is a single line of synced lyrics and the time it starts at.
+type LyricLine struct {
+	Millis uint32
+	Text   string
+}
+
+var (
+	lrcTimestamp = regexp.MustCompile(`\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	lrcTags      = regexp.MustCompile(`^(\[[^\]]*\])+`)
+)
+
+// ParseLRC returns the timed lines of LRC lyrics in order. Lines with several timestamps are repeated for each
+// of them, and ID tags such as [ar:...] are skipped.
+func ParseLRC(lrc string) []LyricLine {
+	lines := make([]LyricLine, 0)
+	for _, line := range strings.Split(lrc, "\n") {
+		line = strings.TrimSpace(line)
+		prefix := lrcTags.FindString(line)
+		if prefix == "" {
+			continue
+		}
+		text := strings.TrimSpace(line[len(prefix):])
+		for _, match := range lrcTimestamp.FindAllStringSubmatch(prefix, -1) {
+			minutes, _ := strconv.Atoi(match[1])
+			seconds, _ := strconv.Atoi(match[2])
+			var millis int
+			if match[3] != "" {
+				// fractions are hundredths in most files, but some use tenths or thousandths
+				millis, _ = strconv.Atoi((match[3] + "00")[:3])
+			}
+			lines = append(lines, LyricLine{Millis: uint32((minutes*60+seconds)*1000 + millis), Text: text})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Millis < lines[j].Millis })
+	return lines
+}
+
+// addLyricsFrames adds plain lyrics as a USLT frame and synced lyrics as a SYLT frame with millisecond timestamps.
+func addLyricsFrames(tag *id3v2.Tag, track *dynamodb.DBTrack) {
+	tag.DeleteFrames("USLT")
+	tag.DeleteFrames("SYLT")
+	if track.Lyrics != "" {
+		tag.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{
+			Encoding: id3v2.EncodingUTF8,
+			Language: "XXX",
+			Lyrics:   track.Lyrics,
+		})
+	}
+	if synced := ParseLRC(track.SyncedLyrics); len(synced) > 0 {
+		tag.AddFrame("SYLT", id3v2.UnknownFrame{Body: syltBody(synced)})
+	}
+}
+
+// syltBody encodes an SYLT frame body, id3v2 has no type for it. The layout is text encoding, language,
+// timestamp format (2 = milliseconds), content type (1 = lyrics), an empty descriptor and then each line
+// as null terminated text followed by its 32 bit timestamp.
+func syltBody(lines []LyricLine) []byte {
+	body := new(bytes.Buffer)
+	body.WriteByte(3)
+	body.WriteString("XXX")
+	body.WriteByte(2)
+	body.WriteByte(1)
+	body.WriteByte(0)
+	for _, line := range lines {
+		body.WriteString(line.Text)
+		body.WriteByte(0)
+		binary.Write(body, binary.BigEndian, line.Millis)
+	}
+	return body.Bytes()
+}
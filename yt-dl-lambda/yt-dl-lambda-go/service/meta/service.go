@@ -4,20 +4,25 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"image"
-	"net/http"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gcottom/go-zaplog"
 	"github.com/gcottom/mp3meta"
 	"github.com/gcottom/retry"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/pkg/coverart"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/dynamodb"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/s3"
 	"go.uber.org/zap"
 )
 
-func (s *Service) SaveMeta(ctx context.Context, data []byte, id string, genre string) error {
+// SaveMeta tags a converted track with its stored metadata and its genres and uploads it under its final name.
+// The classifier's genres, if it ran, are combined with the Spotify artists' genres. Its best tags are kept with
+// their scores and written as the track's moods and styles.
+func (s *Service) SaveMeta(ctx context.Context, data []byte, id string, classified []string, audioTags []dynamodb.TagScore) error {
 	tag, err := mp3meta.ParseMP3(bytes.NewReader(data))
 	if err != nil {
 		zaplog.ErrorC(ctx, "failed to read mp3", zap.Error(err))
@@ -28,44 +33,86 @@ func (s *Service) SaveMeta(ctx context.Context, data []byte, id string, genre st
 		zaplog.ErrorC(ctx, "failed to get track", zap.Error(err))
 		return err
 	}
+	track.Genres, track.GenreSources = s.chooseGenres(ctx, track, classified)
+	if len(track.Genres) > 0 {
+		track.Genre = track.Genres[0]
+	}
+	if len(audioTags) > 0 {
+		track.AudioTags = audioTags
+		track.Moods, track.Styles = s.GenrePolicy.MoodsAndStyles(audioTags)
+	}
 	tag.SetTitle(track.Title)
 	tag.SetArtist(track.Artist)
 	tag.SetAlbum(track.Album)
-	tag.SetGenre(genre)
+	tag.SetAlbumArtist(track.AlbumArtist)
+	tag.SetTrackNumber(track.TrackNumber)
+	tag.SetTrackTotal(track.TrackTotal)
+	tag.SetDiscNumber(track.DiscNumber)
+	tag.SetDiscTotal(track.DiscTotal)
+	tag.SetGenre(track.Genre)
+	tag.SetComposer(track.Composer)
+	tag.SetISRC(track.ISRC)
+	tag.SetDate(track.ReleaseDate)
+	if track.Year > 0 {
+		tag.SetYear(track.Year)
+	}
+	if track.DurationMs > 0 {
+		tag.SetLength(strconv.Itoa(track.DurationMs))
+	}
+	var cover []byte
 	if track.CoverArtURL != "" {
-		response, err := http.Get(track.CoverArtURL)
+		original, err := coverart.Fetch(ctx, s.HTTPClient.Client, track.CoverArtURL, s.CoverOptions.MaxSize)
 		if err != nil {
 			zaplog.ErrorC(ctx, "failed to get cover art", zap.Error(err))
 			return err
 		}
-		defer response.Body.Close()
-		img, _, err := image.Decode(response.Body)
+		cover, err = coverart.Process(original, s.CoverOptions)
 		if err != nil {
-			zaplog.ErrorC(ctx, "failed to decode cover art", zap.Error(err))
+			zaplog.ErrorC(ctx, "failed to process cover art", zap.Error(err))
 			return err
 		}
-		tag.SetCoverArt(&img)
 	}
 	output := new(bytes.Buffer)
 	if err := tag.Save(output); err != nil {
 		zaplog.ErrorC(ctx, "failed to save tag", zap.Error(err))
 		return err
 	}
+	data, err = EmbedExtraFrames(output.Bytes(), track, cover)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to write extra frames", zap.Error(err))
+		return err
+	}
 	fileName := s.SanitizeFilename(fmt.Sprintf("%s - %s.mp3", track.Artist, track.Title))
-	if _, err = retry.Retry(retry.NewAlgSimpleDefault(), 3, s3.UploadToS3, output, fileName, s3.YTDLS3Bucket); err != nil {
+	if _, err = retry.Retry(retry.NewAlgSimpleDefault(), 3, s3.UploadToS3, bytes.NewReader(data), fileName, s3.YTDLS3Bucket); err != nil {
 		zaplog.ErrorC(ctx, "failed to upload to s3", zap.Error(err))
 		return err
 	}
-	if _, err = retry.Retry(retry.NewAlgSimpleDefault(), 3, s.DBClient.PutTrack, ctx,
-		&dynamodb.DBTrack{ID: id, Status: dynamodb.StatusComplete, URL: fileName, FileName: fileName, Title: track.Title, Artist: track.Artist, Album: track.Album}); err != nil {
+	track.Status = dynamodb.StatusComplete
+	track.URL = fileName
+	track.FileName = fileName
+	if _, err = retry.Retry(retry.NewAlgSimpleDefault(), 3, s.DBClient.PutTrack, ctx, track); err != nil {
 		zaplog.ErrorC(ctx, "failed to update dynamodb", zap.Error(err))
 		return err
 	}
 	return nil
 }
 
+// SanitizeFilename makes the object name safe to save on Windows, macOS and Linux: invalid characters are
+// replaced, reserved device names are prefixed and long names are shortened without touching the extension.
 func (s *Service) SanitizeFilename(str string) string {
 	regex := regexp.MustCompile(`[\\/:*?"<>|\x00-\x1F]`)
-	safeStr := regex.ReplaceAllString(str, "_")
-	return strings.Trim(safeStr, " .")
+	safeStr := strings.Trim(regex.ReplaceAllString(str, "_"), " .")
+	ext := path.Ext(safeStr)
+	stem := strings.TrimSuffix(safeStr, ext)
+	if regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[0-9]|lpt[0-9])$`).MatchString(stem) {
+		stem = "_" + stem
+	}
+	if len(stem) > maxFilenameBytes {
+		stem = stem[:maxFilenameBytes]
+		for !utf8.ValidString(stem) {
+			stem = stem[:len(stem)-1]
+		}
+		stem = strings.TrimRight(stem, " .")
+	}
+	return stem + ext
 }
@@ -1,11 +1,16 @@
 package meta
 
 import (
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/pkg/coverart"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/pkg/http_client"
 	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/aws/dynamodb"
+	"github.com/gcottom/yt-dl-3-hybrid/yt-dl-lambda/yt-dl-lambda-go/service/genre"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
+// maxFilenameBytes keeps saved names under the 255 byte limit of common file systems with room for an extension.
+const maxFilenameBytes = 200
+
 type MetaService interface {
 }
 
@@ -13,6 +18,8 @@ type Service struct {
 	HTTPClient    *http_client.HTTPClient
 	SpotifyConfig *clientcredentials.Config
 	DBClient      *dynamodb.DynamoClient
+	CoverOptions  coverart.Options
+	GenrePolicy   genre.Policy
 }
 
 type TrackMeta struct {
@@ -83,6 +83,26 @@ def top_tags(file_name, model='MTT_musicnn', topN=3, input_length=3, input_overl
     return topN_tags
 
 
+def tag_scores(file_name, model='MTT_musicnn', input_length=3, input_overlap=False):
+    ''' Score every tag of the selected model for the music-clip in file_name.
+
+    Takes the same file_name, model, input_length and input_overlap as top_tags.
+
+    OUTPUT
+
+    scores: every tag of the model with its mean likelihood over the clip, most likely first.
+    Data format: list of (tag, likelihood) tuples.
+    Example: [('techno', 0.61), ('synth', 0.34), ...]
+    '''
+
+    if 'vgg' in model and input_length != 3:
+        raise ValueError('Set input_length=3, the VGG models cannot handle different input lengths.')
+
+    taggram, tags = extractor.extractor(file_name, model=model, input_length=input_length, input_overlap=input_overlap, extract_features=False)
+    tags_likelihood_mean = np.mean(taggram, axis=0)
+    return [(tags[tag_index], float(tags_likelihood_mean[tag_index])) for tag_index in tags_likelihood_mean.argsort()[::-1]]
+
+
 def parse_args():
 
     parser = argparse.ArgumentParser(description='Predict the topN tags of the music-clip in file_name with the selected model')
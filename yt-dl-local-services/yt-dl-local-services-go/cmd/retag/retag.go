@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/http_client"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/library"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/retag"
+)
+
+// retag re-resolves the metadata of library files and rewrites their tags. The diff is always shown first and
+// only applied after confirmation, or straight away with -yes.
+//
+//	retag [-dry-run] [-yes] [-replace-cover] [-refresh] [-artist a] [-album a] [-title t] [-genre g] [-q text] [path ...]
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only show the changes")
+	yes := flag.Bool("yes", false, "apply the changes without asking")
+	replaceCover := flag.Bool("replace-cover", false, "also replace the embedded cover art")
+	refresh := flag.Bool("refresh", false, "resolve the metadata again instead of using the cached match")
+	var filter library.Query
+	flag.StringVar(&filter.Artist, "artist", "", "retag library files whose artist contains this")
+	flag.StringVar(&filter.Album, "album", "", "retag library files whose album contains this")
+	flag.StringVar(&filter.Title, "title", "", "retag library files whose title contains this")
+	flag.StringVar(&filter.Genre, "genre", "", "retag library files whose genre contains this")
+	flag.StringVar(&filter.Search, "q", "", "retag library files whose artist, album, title or genre contains this")
+	flag.Parse()
+
+	cfg, err := config.LoadConfigFromFile("")
+	if err != nil {
+		fail(err)
+	}
+	httpClient := http_client.NewHTTPClient()
+	libraryService, err := library.NewLibraryService(cfg)
+	if err != nil {
+		fail(err)
+	}
+	metaService, err := meta.NewMetaService(cfg, httpClient)
+	if err != nil {
+		fail(err)
+	}
+	retagService := retag.NewRetagService(cfg, httpClient, libraryService, metaService)
+
+	req := retag.Request{Paths: flag.Args(), DryRun: true, ReplaceCover: *replaceCover, Refresh: *refresh}
+	if filter != (library.Query{}) {
+		req.Filter = &filter
+	}
+	ctx := context.Background()
+	result, err := retagService.Retag(ctx, req)
+	if err != nil {
+		fail(err)
+	}
+	printResult(result)
+	if *dryRun || result.Changed == 0 {
+		return
+	}
+	if !*yes && !confirm(fmt.Sprintf("apply the changes to %d files? [y/N] ", result.Changed)) {
+		return
+	}
+	apply := retag.Request{Paths: make([]string, 0, result.Changed), ReplaceCover: *replaceCover}
+	for _, file := range result.Files {
+		if file.Status == retag.StatusChanged {
+			apply.Paths = append(apply.Paths, file.Path)
+		}
+	}
+	result, err = retagService.Retag(ctx, apply)
+	if err != nil {
+		fail(err)
+	}
+	for _, file := range result.Files {
+		if file.Status == retag.StatusFailed {
+			fmt.Printf("%s: %s\n", file.Path, file.Reason)
+		}
+	}
+	fmt.Printf("%d retagged, %d unchanged, %d skipped, %d failed\n", result.Changed, result.Unchanged, result.Skipped, result.Failed)
+}
+
+func printResult(result *retag.Result) {
+	for _, file := range result.Files {
+		switch file.Status {
+		case retag.StatusChanged:
+			fmt.Printf("%s (from %s)\n", file.Path, file.Source)
+			for _, change := range file.Changes {
+				fmt.Printf("  %-28s %q -> %q\n", change.Field, change.Old, change.New)
+			}
+		case retag.StatusSkipped, retag.StatusFailed:
+			fmt.Printf("%s: %s, %s\n", file.Path, file.Status, file.Reason)
+		}
+	}
+	fmt.Printf("%d to change, %d unchanged, %d skipped, %d failed\n", result.Changed, result.Unchanged, result.Skipped, result.Failed)
+}
+
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -12,6 +12,10 @@ import (
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/handlers"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/http_client"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/downloader"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/library"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/playlists"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/retag"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/subscriptions"
 	"github.com/gin-contrib/cors"
 )
 
@@ -32,11 +36,37 @@ func RunServer(cfg *config.Config) error {
 	zaplog.InfoC(ctx, "creating http client")
 	httpClient := http_client.NewHTTPClient()
 
+	zaplog.InfoC(ctx, "creating library service")
+	libraryService, err := library.NewLibraryService(cfg)
+	if err != nil {
+		return err
+	}
+
+	zaplog.InfoC(ctx, "creating playlist service")
+	playlistService, err := playlists.NewPlaylistService(cfg, libraryService)
+	if err != nil {
+		return err
+	}
+
 	zaplog.InfoC(ctx, "creating download service")
-	downloaderService := downloader.NewDownloaderService(cfg, httpClient)
+	downloaderService, err := downloader.NewDownloaderService(cfg, httpClient, libraryService, playlistService)
+	if err != nil {
+		return err
+	}
+
+	zaplog.InfoC(ctx, "creating subscription service")
+	subscriptionService, err := subscriptions.NewSubscriptionService(cfg, downloaderService, downloaderService.YoutubeClient)
+	if err != nil {
+		return err
+	}
+
+	zaplog.InfoC(ctx, "creating retag service")
+	retagService := retag.NewRetagService(cfg, httpClient, libraryService, downloaderService.MetaServiceClient)
 
 	go downloaderService.DLQueueProcessor()
 	go downloaderService.StatusProcessor()
+	go subscriptionService.Scheduler()
+	go libraryService.Scanner()
 
 	zaplog.InfoC(ctx, "creating gin engine")
 	ginws := qgin.NewGinEngine(&ctx, &qgin.Config{
@@ -49,7 +79,7 @@ func RunServer(cfg *config.Config) error {
 	})
 	ginws.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true,
-		AllowMethods:     []string{"GET", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Accept"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
@@ -57,7 +87,7 @@ func RunServer(cfg *config.Config) error {
 	}))
 
 	zaplog.InfoC(ctx, "setting up routes")
-	handlers.SetupRoutes(ginws, downloaderService)
+	handlers.SetupRoutes(ginws, downloaderService, subscriptionService, libraryService, playlistService, retagService, downloaderService.MetaServiceClient)
 
 	zaplog.InfoC(ctx, fmt.Sprintf("serving on port %d", cfg.LocalPort))
 	return http.ListenAndServe(fmt.Sprintf(":%d", cfg.LocalPort), ginws)
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/coverart"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/pathtemplate"
 	"gopkg.in/yaml.v2"
 )
 
@@ -21,16 +24,140 @@ func LoadConfigFromFile(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	config.setDefaults()
+	if err := pathtemplate.Validate(config.FilenameTemplate); err != nil {
+		return nil, fmt.Errorf("invalid filename_template: %w", err)
+	}
+	switch config.MetadataReviewDefaultAction {
+	case "youtube", "best", "fail":
+	default:
+		return nil, fmt.Errorf("invalid metadata_review_default_action: %q, must be youtube, best or fail", config.MetadataReviewDefaultAction)
+	}
+	switch config.FeaturedArtistStyle {
+	case "title", "artist", "keep":
+	default:
+		return nil, fmt.Errorf("invalid featured_artist_style: %q, must be title, artist or keep", config.FeaturedArtistStyle)
+	}
+	switch config.DuplicateAction {
+	case "off", "flag", "skip":
+	default:
+		return nil, fmt.Errorf("invalid duplicate_action: %q, must be off, flag or skip", config.DuplicateAction)
+	}
+	for _, provider := range config.MetadataProviders {
+		if provider != "spotify" && provider != "musicbrainz" {
+			return nil, fmt.Errorf("invalid metadata_providers: unknown provider %q", provider)
+		}
+	}
 	return &config, nil
 }
 
 type Config struct {
-	LambdaDomain        string `yaml:"lambda_domain"`
-	LocalPort           int    `yaml:"local_port_go_services"`
-	LocalPortPython     int    `yaml:"local_port_python_services"`
-	ConcurrentDownloads int    `yaml:"concurrent_downloads"`
-	SaveDir             string `yaml:"save_dir"`
-	TempDir             string `yaml:"temp_dir"`
-	SpotifyClientID     string `yaml:"spotify_client_id"`
-	SpotifyClientSecret string `yaml:"spotify_client_secret"`
+	LambdaDomain                     string   `yaml:"lambda_domain"`
+	LocalPort                        int      `yaml:"local_port_go_services"`
+	LocalPortPython                  int      `yaml:"local_port_python_services"`
+	ConcurrentDownloads              int      `yaml:"concurrent_downloads"`
+	SaveDir                          string   `yaml:"save_dir"`
+	TempDir                          string   `yaml:"temp_dir"`
+	StateDir                         string   `yaml:"state_dir"`
+	FilenameTemplate                 string   `yaml:"filename_template"`
+	PlaylistAsAlbum                  bool     `yaml:"playlist_as_album"`
+	SpotifyClientID                  string   `yaml:"spotify_client_id"`
+	SpotifyClientSecret              string   `yaml:"spotify_client_secret"`
+	SpotifyMaxBackoffSeconds         int      `yaml:"spotify_max_backoff_seconds"`
+	MetadataProviders                []string `yaml:"metadata_providers"`
+	MetadataMatchThreshold           float64  `yaml:"metadata_match_threshold"`
+	MetadataDurationToleranceSeconds int      `yaml:"metadata_duration_tolerance_seconds"`
+	MetadataTransliterate            bool     `yaml:"metadata_transliterate"`
+	FeaturedArtistStyle              string   `yaml:"featured_artist_style"`
+	ArtistSeparator                  string   `yaml:"artist_separator"`
+	DisableMetadataReview            bool     `yaml:"disable_metadata_review"`
+	MetadataReviewMinScore           float64  `yaml:"metadata_review_min_score"`
+	MetadataReviewTimeoutMinutes     int      `yaml:"metadata_review_timeout_minutes"`
+	MetadataReviewDefaultAction      string   `yaml:"metadata_review_default_action"`
+	MusicBrainzURL                   string   `yaml:"musicbrainz_url"`
+	DisableMetaCache                 bool     `yaml:"disable_meta_cache"`
+	MetaCacheTTLHours                int      `yaml:"meta_cache_ttl_hours"`
+	CoverArtArchiveURL               string   `yaml:"cover_art_archive_url"`
+	CoverArtMaxSize                  int      `yaml:"cover_art_max_size"`
+	CoverArtMaxBytes                 int      `yaml:"cover_art_max_bytes"`
+	SubscriptionMinIntervalMinutes   int      `yaml:"subscription_min_interval_minutes"`
+	SubscriptionMaxNewEntriesPerRun  int      `yaml:"subscription_max_new_entries_per_run"`
+	LibraryScanIntervalMinutes       int      `yaml:"library_scan_interval_minutes"`
+	DisableLyrics                    bool     `yaml:"disable_lyrics"`
+	LyricsAPIURL                     string   `yaml:"lyrics_api_url"`
+	LyricsSidecar                    bool     `yaml:"lyrics_sidecar"`
+	CoverSidecar                     bool     `yaml:"cover_sidecar"`
+	InfoSidecar                      bool     `yaml:"info_sidecar"`
+	DuplicateAction                  string   `yaml:"duplicate_action"`
+	DuplicateThreshold               float64  `yaml:"duplicate_threshold"`
+}
+
+// setDefaults fills in values for optional settings that were left out of the config file.
+func (c *Config) setDefaults() {
+	if c.StateDir == "" {
+		c.StateDir = "./state"
+	}
+	if c.FilenameTemplate == "" {
+		c.FilenameTemplate = "{artist} - {title}.{ext}"
+	}
+	if c.SubscriptionMinIntervalMinutes <= 0 {
+		c.SubscriptionMinIntervalMinutes = 15
+	}
+	if c.SubscriptionMaxNewEntriesPerRun <= 0 {
+		c.SubscriptionMaxNewEntriesPerRun = 10
+	}
+	if c.LibraryScanIntervalMinutes <= 0 {
+		c.LibraryScanIntervalMinutes = 30
+	}
+	if c.SpotifyMaxBackoffSeconds <= 0 {
+		c.SpotifyMaxBackoffSeconds = 120
+	}
+	if len(c.MetadataProviders) == 0 {
+		c.MetadataProviders = []string{"spotify", "musicbrainz"}
+	}
+	if c.MetadataMatchThreshold <= 0 {
+		c.MetadataMatchThreshold = 0.8
+	}
+	if c.MetadataDurationToleranceSeconds <= 0 {
+		c.MetadataDurationToleranceSeconds = 15
+	}
+	if c.MetadataReviewMinScore <= 0 {
+		c.MetadataReviewMinScore = 0.5
+	}
+	if c.MetadataReviewTimeoutMinutes <= 0 {
+		c.MetadataReviewTimeoutMinutes = 60
+	}
+	if c.MetadataReviewDefaultAction == "" {
+		c.MetadataReviewDefaultAction = "youtube"
+	}
+	if c.FeaturedArtistStyle == "" {
+		c.FeaturedArtistStyle = "title"
+	}
+	if c.ArtistSeparator == "" {
+		c.ArtistSeparator = ", "
+	}
+	if c.MetaCacheTTLHours <= 0 {
+		c.MetaCacheTTLHours = 720
+	}
+	if c.MusicBrainzURL == "" {
+		c.MusicBrainzURL = "https://musicbrainz.org"
+	}
+	if c.CoverArtArchiveURL == "" {
+		c.CoverArtArchiveURL = "https://coverartarchive.org"
+	}
+	if c.CoverArtMaxSize <= 0 {
+		c.CoverArtMaxSize = coverart.DefaultMaxSize
+	}
+	if c.CoverArtMaxBytes <= 0 {
+		c.CoverArtMaxBytes = coverart.DefaultMaxBytes
+	}
+	if c.LyricsAPIURL == "" {
+		c.LyricsAPIURL = "https://lrclib.net"
+	}
+	if c.DuplicateAction == "" {
+		c.DuplicateAction = "flag"
+	}
+	if c.DuplicateThreshold <= 0 {
+		c.DuplicateThreshold = 0.75
+	}
 }
@@ -5,6 +5,7 @@ go 1.22.3
 replace github.com/kkdai/youtube/v2 => github.com/ruizlenato/youtube/v2 v2.0.0-20241207180344-3f95c0c6982d
 
 require (
+	github.com/bogem/id3v2/v2 v2.1.4
 	github.com/gcottom/go-zaplog v0.0.3
 	github.com/gcottom/qgin v0.0.10
 	github.com/gcottom/retry v0.1.1
@@ -14,7 +15,9 @@ require (
 	github.com/kkdai/youtube/v2 v2.10.1
 	github.com/zmb3/spotify/v2 v2.4.2
 	go.uber.org/zap v1.27.0
+	golang.org/x/image v0.18.0
 	golang.org/x/oauth2 v0.23.0
+	golang.org/x/text v0.20.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
@@ -49,7 +52,6 @@ require (
 	golang.org/x/crypto v0.29.0 // indirect
 	golang.org/x/net v0.31.0 // indirect
 	golang.org/x/sys v0.27.0 // indirect
-	golang.org/x/text v0.20.0 // indirect
 	google.golang.org/protobuf v1.34.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
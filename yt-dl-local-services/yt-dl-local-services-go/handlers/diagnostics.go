@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) GetDiagnostics(ctx *gin.Context) {
+	zaplog.InfoC(ctx, "diagnostics request received")
+	ResponseSuccess(ctx, h.MetaService.Diagnostics(ctx))
+}
+
+func (h *Handler) GetCachedMeta(ctx *gin.Context) {
+	id := ctx.Query("id")
+	if id == "" {
+		zaplog.InfoC(ctx, "list meta cache request received")
+		entries, err := h.MetaService.ListCachedMeta(ctx)
+		if err != nil {
+			zaplog.WarnC(ctx, "error listing meta cache", zap.Error(err))
+			ResponseFailure(ctx, err)
+			return
+		}
+		ResponseSuccess(ctx, entries)
+		return
+	}
+	zaplog.InfoC(ctx, "get cached meta request received", zap.String("id", id))
+	entry, err := h.MetaService.GetCachedMeta(ctx, id)
+	if err != nil {
+		zaplog.WarnC(ctx, "error getting cached meta", zap.String("id", id), zap.Error(err))
+		if errors.Is(err, meta.ErrNotCached) {
+			ResponseNotFound(ctx, err)
+			return
+		}
+		ResponseFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, entry)
+}
+
+func (h *Handler) InvalidateCachedMeta(ctx *gin.Context) {
+	id := ctx.Query("id")
+	if id == "" {
+		if ctx.Query("all") != "true" {
+			zaplog.WarnC(ctx, "invalidate meta cache request without ID present: ID or all=true is required")
+			ResponseFailure(ctx, errors.New("invalidate meta cache request without ID present: ID or all=true is required"))
+			return
+		}
+		zaplog.InfoC(ctx, "clear meta cache request received")
+		removed, err := h.MetaService.ClearCachedMeta(ctx)
+		if err != nil {
+			zaplog.ErrorC(ctx, "error clearing meta cache", zap.Error(err))
+			ResponseFailure(ctx, err)
+			return
+		}
+		ResponseSuccess(ctx, InvalidateCacheResponse{Removed: removed})
+		return
+	}
+	zaplog.InfoC(ctx, "invalidate cached meta request received", zap.String("id", id))
+	if err := h.MetaService.InvalidateCachedMeta(ctx, id); err != nil {
+		zaplog.WarnC(ctx, "error invalidating cached meta", zap.String("id", id), zap.Error(err))
+		if errors.Is(err, meta.ErrNotCached) {
+			ResponseNotFound(ctx, err)
+			return
+		}
+		ResponseFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, InvalidateCacheResponse{Removed: 1})
+}
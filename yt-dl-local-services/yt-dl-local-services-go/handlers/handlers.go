@@ -2,15 +2,27 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/gcottom/go-zaplog"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/downloader"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/library"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/playlists"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/retag"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/subscriptions"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	DownloaderService downloader.DownloaderService
+	DownloaderService   downloader.DownloaderService
+	SubscriptionService subscriptions.SubscriptionService
+	LibraryService      library.LibraryService
+	PlaylistService     playlists.PlaylistService
+	RetagService        retag.RetagService
+	MetaService         meta.MetaService
 }
 
 func (h *Handler) StartDownload(ctx *gin.Context) {
@@ -21,7 +33,51 @@ func (h *Handler) StartDownload(ctx *gin.Context) {
 		return
 	}
 	zaplog.InfoC(ctx, "starting download request received", zap.String("id", id))
-	if err := h.DownloaderService.InitiateDownload(ctx, id); err != nil {
+	opts := downloader.DownloadOptions{Template: ctx.Query("template")}
+	if asAlbum, ok := ctx.GetQuery("playlist_as_album"); ok {
+		value, err := strconv.ParseBool(asAlbum)
+		if err != nil {
+			zaplog.WarnC(ctx, "start download request with invalid playlist_as_album", zap.String("playlist_as_album", asAlbum))
+			ResponseFailure(ctx, fmt.Errorf("invalid playlist_as_album value %q", asAlbum))
+			return
+		}
+		opts.PlaylistAsAlbum = &value
+	}
+	var overrides meta.Overrides
+	if err := ctx.ShouldBindQuery(&overrides); err != nil {
+		zaplog.WarnC(ctx, "start download request with invalid overrides", zap.Error(err))
+		ResponseFailure(ctx, fmt.Errorf("invalid overrides: %w", err))
+		return
+	}
+	if overrides != (meta.Overrides{}) {
+		opts.Overrides = &overrides
+	}
+	h.startDownload(ctx, id, opts)
+}
+
+// StartDownloadJSON is the POST variant of StartDownload, taking the options and metadata overrides as a JSON body.
+func (h *Handler) StartDownloadJSON(ctx *gin.Context) {
+	var req StartDownloadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		zaplog.WarnC(ctx, "invalid start download request", zap.Error(err))
+		ResponseFailure(ctx, err)
+		return
+	}
+	if req.ID == "" {
+		zaplog.WarnC(ctx, "start download request without ID present: ID is required")
+		ResponseFailure(ctx, errors.New("start download request without ID present: ID is required"))
+		return
+	}
+	zaplog.InfoC(ctx, "starting download request received", zap.String("id", req.ID))
+	opts := downloader.DownloadOptions{Template: req.Template, PlaylistAsAlbum: req.PlaylistAsAlbum}
+	if req.Overrides != (meta.Overrides{}) {
+		opts.Overrides = &req.Overrides
+	}
+	h.startDownload(ctx, req.ID, opts)
+}
+
+func (h *Handler) startDownload(ctx *gin.Context, id string, opts downloader.DownloadOptions) {
+	if err := h.DownloaderService.InitiateDownload(ctx, id, opts); err != nil {
 		zaplog.ErrorC(ctx, "error starting download request", zap.Error(err))
 		ResponseFailure(ctx, err)
 		return
@@ -64,3 +120,29 @@ func (h *Handler) AcknowledgeWarning(ctx *gin.Context) {
 	h.DownloaderService.AcknowledgeWarning(ctx, id)
 	ResponseSuccess(ctx, StartDownloadResponse{State: "ACK"})
 }
+
+func (h *Handler) SubmitReview(ctx *gin.Context) {
+	id := ctx.Query("id")
+	if id == "" {
+		zaplog.WarnC(ctx, "submit review request without ID present: ID is required")
+		ResponseFailure(ctx, errors.New("submit review request without ID present: ID is required"))
+		return
+	}
+	var decision downloader.ReviewDecision
+	if err := ctx.ShouldBindJSON(&decision); err != nil {
+		zaplog.WarnC(ctx, "invalid submit review request", zap.Error(err))
+		ResponseFailure(ctx, err)
+		return
+	}
+	zaplog.InfoC(ctx, "submit review request received", zap.String("id", id))
+	if err := h.DownloaderService.SubmitReview(ctx, id, decision); err != nil {
+		zaplog.WarnC(ctx, "error submitting review", zap.String("id", id), zap.Error(err))
+		if errors.Is(err, downloader.ErrNoReview) {
+			ResponseNotFound(ctx, err)
+			return
+		}
+		ResponseFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, StartDownloadResponse{State: "ACK"})
+}
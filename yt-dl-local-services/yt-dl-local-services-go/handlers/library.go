@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/library"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/retag"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) QueryLibrary(ctx *gin.Context) {
+	var query library.Query
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		zaplog.WarnC(ctx, "invalid library query", zap.Error(err))
+		ResponseFailure(ctx, err)
+		return
+	}
+	page, err := h.LibraryService.Query(ctx, query)
+	if err != nil {
+		zaplog.WarnC(ctx, "library query failed", zap.Error(err))
+		ResponseFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, page)
+}
+
+func (h *Handler) ScanLibrary(ctx *gin.Context) {
+	zaplog.InfoC(ctx, "library scan request received")
+	result, err := h.LibraryService.Scan(ctx)
+	if err != nil {
+		zaplog.ErrorC(ctx, "library scan failed", zap.Error(err))
+		ResponseInternalError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, result)
+}
+
+func (h *Handler) LibraryDuplicates(ctx *gin.Context) {
+	report, err := h.LibraryService.Duplicates(ctx)
+	if err != nil {
+		zaplog.ErrorC(ctx, "library duplicates report failed", zap.Error(err))
+		ResponseInternalError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, report)
+}
+
+func (h *Handler) RetagLibrary(ctx *gin.Context) {
+	var req retag.Request
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		zaplog.WarnC(ctx, "invalid retag request", zap.Error(err))
+		ResponseFailure(ctx, err)
+		return
+	}
+	zaplog.InfoC(ctx, "retag request received", zap.Int("paths", len(req.Paths)), zap.Bool("filter", req.Filter != nil), zap.Bool("dryRun", req.DryRun))
+	result, err := h.RetagService.Retag(ctx, req)
+	if err != nil {
+		zaplog.WarnC(ctx, "retag failed", zap.Error(err))
+		ResponseFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, result)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/playlists"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) ExportPlaylist(ctx *gin.Context) {
+	id := ctx.Query("id")
+	if id == "" {
+		zaplog.WarnC(ctx, "export playlist request without ID present: ID is required")
+		ResponseFailure(ctx, errors.New("export playlist request without ID present: ID is required"))
+		return
+	}
+	format := ctx.DefaultQuery("format", playlists.FormatM3U8)
+	zaplog.InfoC(ctx, "export playlist request received", zap.String("id", id), zap.String("format", format))
+	export, err := h.PlaylistService.Export(ctx, id, format)
+	if err != nil {
+		zaplog.ErrorC(ctx, "error exporting playlist", zap.Error(err))
+		if errors.Is(err, playlists.ErrNotFound) {
+			ResponseNotFound(ctx, err)
+			return
+		}
+		ResponseFailure(ctx, err)
+		return
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.FileName))
+	ctx.Data(200, export.ContentType, export.Data)
+}
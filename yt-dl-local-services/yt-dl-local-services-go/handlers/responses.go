@@ -1,15 +1,30 @@
 package handlers
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"github.com/gin-gonic/gin"
+)
 
 type Failure struct {
 	Error string `json:"error"`
 }
 
+// StartDownloadRequest is the body of POST /download, the overrides sit next to the options at the top level.
+type StartDownloadRequest struct {
+	ID              string `json:"id"`
+	Template        string `json:"template,omitempty"`
+	PlaylistAsAlbum *bool  `json:"playlist_as_album,omitempty"`
+	meta.Overrides
+}
+
 type StartDownloadResponse struct {
 	State string `json:"state"`
 }
 
+type InvalidateCacheResponse struct {
+	Removed int `json:"removed"`
+}
+
 type StatusUpdate struct {
 	ID                 string `json:"id"`
 	Status             string `json:"status"`
@@ -21,6 +36,10 @@ func ResponseFailure(ctx *gin.Context, err error) {
 	ctx.AbortWithError(400, err)
 }
 
+func ResponseNotFound(ctx *gin.Context, err error) {
+	ctx.AbortWithError(404, err)
+}
+
 func ResponseInternalError(ctx *gin.Context, err error) {
 	ctx.AbortWithError(500, err)
 }
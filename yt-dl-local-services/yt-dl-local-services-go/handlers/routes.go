@@ -2,12 +2,44 @@ package handlers
 
 import (
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/downloader"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/library"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/playlists"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/retag"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/subscriptions"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, downloaderService downloader.DownloaderService) {
-	handler := &Handler{DownloaderService: downloaderService}
+func SetupRoutes(router *gin.Engine, downloaderService downloader.DownloaderService, subscriptionService subscriptions.SubscriptionService, libraryService library.LibraryService, playlistService playlists.PlaylistService, retagService retag.RetagService, metaService meta.MetaService) {
+	handler := &Handler{
+		DownloaderService:   downloaderService,
+		SubscriptionService: subscriptionService,
+		LibraryService:      libraryService,
+		PlaylistService:     playlistService,
+		RetagService:        retagService,
+		MetaService:         metaService,
+	}
 	router.GET("/download", handler.StartDownload)
+	router.POST("/download", handler.StartDownloadJSON)
 	router.GET("/status", handler.GetStatus)
 	router.GET("/acknowledge", handler.AcknowledgeWarning)
+	router.POST("/review", handler.SubmitReview)
+
+	router.GET("/subscriptions", handler.ListSubscriptions)
+	router.GET("/subscription", handler.GetSubscription)
+	router.POST("/subscription", handler.CreateSubscription)
+	router.PUT("/subscription", handler.UpdateSubscription)
+	router.DELETE("/subscription", handler.DeleteSubscription)
+	router.POST("/subscription/sync", handler.SyncSubscription)
+
+	router.GET("/library", handler.QueryLibrary)
+	router.POST("/library/scan", handler.ScanLibrary)
+	router.POST("/library/retag", handler.RetagLibrary)
+	router.GET("/library/duplicates", handler.LibraryDuplicates)
+
+	router.GET("/playlist/export", handler.ExportPlaylist)
+
+	router.GET("/diagnostics", handler.GetDiagnostics)
+	router.GET("/meta/cache", handler.GetCachedMeta)
+	router.DELETE("/meta/cache", handler.InvalidateCachedMeta)
 }
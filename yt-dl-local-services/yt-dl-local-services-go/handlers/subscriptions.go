@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/subscriptions"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) ListSubscriptions(ctx *gin.Context) {
+	subs, err := h.SubscriptionService.ListSubscriptions(ctx)
+	if err != nil {
+		zaplog.ErrorC(ctx, "error listing subscriptions", zap.Error(err))
+		ResponseInternalError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, subs)
+}
+
+func (h *Handler) GetSubscription(ctx *gin.Context) {
+	id := ctx.Query("id")
+	if id == "" {
+		zaplog.WarnC(ctx, "get subscription request without ID present: ID is required")
+		ResponseFailure(ctx, errors.New("get subscription request without ID present: ID is required"))
+		return
+	}
+	sub, err := h.SubscriptionService.GetSubscription(ctx, id)
+	if err != nil {
+		subscriptionFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, sub)
+}
+
+func (h *Handler) CreateSubscription(ctx *gin.Context) {
+	var req subscriptions.SubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		zaplog.WarnC(ctx, "invalid create subscription request", zap.Error(err))
+		ResponseFailure(ctx, err)
+		return
+	}
+	zaplog.InfoC(ctx, "create subscription request received", zap.String("playlistID", req.PlaylistID))
+	sub, err := h.SubscriptionService.CreateSubscription(ctx, req)
+	if err != nil {
+		subscriptionFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, sub)
+}
+
+func (h *Handler) UpdateSubscription(ctx *gin.Context) {
+	id := ctx.Query("id")
+	if id == "" {
+		zaplog.WarnC(ctx, "update subscription request without ID present: ID is required")
+		ResponseFailure(ctx, errors.New("update subscription request without ID present: ID is required"))
+		return
+	}
+	var req subscriptions.SubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		zaplog.WarnC(ctx, "invalid update subscription request", zap.Error(err))
+		ResponseFailure(ctx, err)
+		return
+	}
+	sub, err := h.SubscriptionService.UpdateSubscription(ctx, id, req)
+	if err != nil {
+		subscriptionFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, sub)
+}
+
+func (h *Handler) DeleteSubscription(ctx *gin.Context) {
+	id := ctx.Query("id")
+	if id == "" {
+		zaplog.WarnC(ctx, "delete subscription request without ID present: ID is required")
+		ResponseFailure(ctx, errors.New("delete subscription request without ID present: ID is required"))
+		return
+	}
+	if err := h.SubscriptionService.DeleteSubscription(ctx, id); err != nil {
+		subscriptionFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, StartDownloadResponse{State: "ACK"})
+}
+
+func (h *Handler) SyncSubscription(ctx *gin.Context) {
+	id := ctx.Query("id")
+	if id == "" {
+		zaplog.WarnC(ctx, "sync subscription request without ID present: ID is required")
+		ResponseFailure(ctx, errors.New("sync subscription request without ID present: ID is required"))
+		return
+	}
+	sub, err := h.SubscriptionService.SyncSubscription(ctx, id)
+	if err != nil {
+		subscriptionFailure(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, sub)
+}
+
+func subscriptionFailure(ctx *gin.Context, err error) {
+	zaplog.ErrorC(ctx, "subscription request failed", zap.Error(err))
+	if errors.Is(err, subscriptions.ErrNotFound) {
+		ResponseNotFound(ctx, err)
+		return
+	}
+	ResponseFailure(ctx, err)
+}
@@ -0,0 +1,156 @@
+package artists
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/textnorm"
+)
+
+// Credit is an artist credit split into the main artists and the featured ones.
+type Credit struct {
+	Primary  []string `json:"primary"`
+	Featured []string `json:"featured,omitempty"`
+}
+
+var (
+	// featMarker only matches the markers as whole words between spaces, so "Feather" or "Daft Punk" never split
+	featMarker = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+`)
+	withMarker = regexp.MustCompile(`(?i)\s+with\s+`)
+	// featJoin matches a join phrase between two credited names that makes the second one a featured artist
+	featJoin = regexp.MustCompile(`(?i)^\s*(?:feat\.?|ft\.?|featuring|with)\s*$`)
+	// listSeparator separates artists credited side by side, "&" and "," followed by "the" are part of a band name
+	// like "Hootie & the Blowfish" and are skipped by splitList. Only a lowercase "x" joins artists, "Mr. X" is a name
+	listSeparator = regexp.MustCompile(`(?i:\s*,\s*|\s+&\s+|\s+\+\s+|\s+vs\.?\s+)|\s+x\s+|\s+×\s+`)
+	// bracketedFeat matches "(feat. X)", "[ft. X]" and "(with X)" in a title
+	bracketedFeat = regexp.MustCompile(`(?i)\s*[(\[]\s*(?:feat\.?|ft\.?|featuring|with)\s+([^()\[\]]+?)\s*[)\]]`)
+	// trailingFeat matches an unbracketed "ft. X" in a title, it ends at a " - " or an opening bracket
+	trailingFeat = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+([^()\[\]]+?)(\s+-\s+.*|\s*[(\[].*)?$`)
+)
+
+// Parse splits an artist credit such as "A & B feat. C, D" or a channel name into the primary and featured
+// artists. Names are split on every separator, so a band like "Mumford & Sons" comes back as two artists. That is
+// fine for matching, but the credit should only be rewritten from the result when it has featured artists.
+func Parse(credit string) Credit {
+	primary, featured := Split(credit)
+	if primary == "" {
+		return Credit{}
+	}
+	out := Credit{Primary: splitList(primary)}
+	out.AddFeatured(splitList(featured)...)
+	return out
+}
+
+// Split cuts an artist credit at its first feat. or with marker, "A & B feat. C, D" gives "A & B" and "C, D".
+// A credit without a marker is all primary.
+func Split(credit string) (string, string) {
+	credit = strings.TrimSpace(credit)
+	if loc := featMarker.FindStringIndex(credit); loc != nil {
+		return strings.TrimSpace(credit[:loc[0]]), strings.TrimSpace(credit[loc[1]:])
+	}
+	if loc := withMarker.FindStringIndex(credit); loc != nil {
+		return strings.TrimSpace(credit[:loc[0]]), strings.TrimSpace(credit[loc[1]:])
+	}
+	return credit, ""
+}
+
+// IsFeatureJoin reports whether a join phrase between two credited artists, like MusicBrainz's " feat. ", makes
+// the artists after it featured ones.
+func IsFeatureJoin(phrase string) bool {
+	return featJoin.MatchString(phrase)
+}
+
+// ParseTitle removes the featured artists from a title and returns them, "Song (feat. A & B) [Live]" gives
+// "Song [Live]" with A and B.
+func ParseTitle(title string) (string, []string) {
+	featured := make([]string, 0)
+	for _, match := range bracketedFeat.FindAllStringSubmatch(title, -1) {
+		featured = append(featured, splitList(match[1])...)
+	}
+	title = bracketedFeat.ReplaceAllString(title, "")
+	if match := trailingFeat.FindStringSubmatchIndex(title); match != nil {
+		featured = append(featured, splitList(title[match[2]:match[3]])...)
+		rest := ""
+		if match[4] >= 0 {
+			rest = title[match[4]:match[5]]
+		}
+		title = title[:match[0]] + rest
+	}
+	return strings.TrimSpace(title), featured
+}
+
+// AddFeatured adds featured artists that are not credited yet and moves primary artists named among them to the
+// featured list.
+func (c *Credit) AddFeatured(names ...string) {
+	for _, name := range names {
+		if contains(c.Featured, name) {
+			continue
+		}
+		c.Featured = append(c.Featured, name)
+		primary := make([]string, 0, len(c.Primary))
+		for _, p := range c.Primary {
+			if !textnorm.Equal(p, name) {
+				primary = append(primary, p)
+			}
+		}
+		if len(primary) > 0 {
+			c.Primary = primary
+		}
+	}
+}
+
+// All returns every credited artist, the primary artists first.
+func (c Credit) All() []string {
+	all := append(make([]string, 0, len(c.Primary)+len(c.Featured)), c.Primary...)
+	for _, name := range c.Featured {
+		if !contains(all, name) {
+			all = append(all, name)
+		}
+	}
+	return all
+}
+
+// Format writes the credit as "A, B feat. C" with the given separator between artists.
+func (c Credit) Format(separator string) string {
+	str := strings.Join(c.Primary, separator)
+	if len(c.Featured) > 0 {
+		str += " feat. " + strings.Join(c.Featured, separator)
+	}
+	return str
+}
+
+// FormatTitle appends the featured artists to a title as "Title (feat. A, B)".
+func FormatTitle(title string, featured []string, separator string) string {
+	if len(featured) == 0 {
+		return title
+	}
+	return title + " (feat. " + strings.Join(featured, separator) + ")"
+}
+
+// splitList splits artists credited side by side on commas, "&", "+", "x" and "vs.".
+func splitList(str string) []string {
+	names := make([]string, 0)
+	start := 0
+	for _, loc := range listSeparator.FindAllStringIndex(str, -1) {
+		if strings.HasPrefix(strings.ToLower(str[loc[1]:]), "the ") {
+			continue
+		}
+		if name := strings.TrimSpace(str[start:loc[0]]); name != "" {
+			names = append(names, name)
+		}
+		start = loc[1]
+	}
+	if name := strings.TrimSpace(str[start:]); name != "" {
+		names = append(names, name)
+	}
+	return names
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if textnorm.Equal(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package artists
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		credit string
+		want   Credit
+	}{
+		{"", Credit{}},
+		{"Daft Punk", Credit{Primary: []string{"Daft Punk"}}},
+		{"Feather", Credit{Primary: []string{"Feather"}}},
+		{"A & B", Credit{Primary: []string{"A", "B"}}},
+		{"A, B & C", Credit{Primary: []string{"A", "B", "C"}}},
+		{"A x B", Credit{Primary: []string{"A", "B"}}},
+		{"A vs. B", Credit{Primary: []string{"A", "B"}}},
+		{"A feat. B", Credit{Primary: []string{"A"}, Featured: []string{"B"}}},
+		{"A ft. B & C", Credit{Primary: []string{"A"}, Featured: []string{"B", "C"}}},
+		{"A & B featuring C", Credit{Primary: []string{"A", "B"}, Featured: []string{"C"}}},
+		{"A with B", Credit{Primary: []string{"A"}, Featured: []string{"B"}}},
+		{"Hootie & the Blowfish", Credit{Primary: []string{"Hootie & the Blowfish"}}},
+		{"Mr. X & Co", Credit{Primary: []string{"Mr. X", "Co"}}},
+		// band names are split too, callers keep the credit as written unless it has featured artists
+		{"Mumford & Sons", Credit{Primary: []string{"Mumford", "Sons"}}},
+		{"Earth, Wind & Fire", Credit{Primary: []string{"Earth", "Wind", "Fire"}}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.credit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.credit, got, tt.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		credit   string
+		primary  string
+		featured string
+	}{
+		{"Mumford & Sons", "Mumford & Sons", ""},
+		{" A & B feat. C, D ", "A & B", "C, D"},
+		{"A with B", "A", "B"},
+		{"A Ft. B", "A", "B"},
+	}
+	for _, tt := range tests {
+		primary, featured := Split(tt.credit)
+		if primary != tt.primary || featured != tt.featured {
+			t.Errorf("Split(%q) = %q, %q, want %q, %q", tt.credit, primary, featured, tt.primary, tt.featured)
+		}
+	}
+}
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		title    string
+		want     string
+		featured []string
+	}{
+		{"Song", "Song", []string{}},
+		{"Song (feat. A & B) [Live]", "Song [Live]", []string{"A", "B"}},
+		{"Song [ft. A]", "Song", []string{"A"}},
+		{"Song (with A)", "Song", []string{"A"}},
+		{"Song ft. A - Remix", "Song - Remix", []string{"A"}},
+		{"Feather", "Feather", []string{}},
+	}
+	for _, tt := range tests {
+		got, featured := ParseTitle(tt.title)
+		if got != tt.want || !reflect.DeepEqual(featured, tt.featured) {
+			t.Errorf("ParseTitle(%q) = %q, %v, want %q, %v", tt.title, got, featured, tt.want, tt.featured)
+		}
+	}
+}
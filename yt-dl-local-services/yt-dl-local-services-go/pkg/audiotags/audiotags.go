@@ -0,0 +1,270 @@
+package audiotags
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+)
+
+// Tags is the subset of a file's metadata that the local services care about.
+type Tags struct {
+	Title  string   `json:"title"`
+	Artist string   `json:"artist"`
+	Album  string   `json:"album"`
+	Genre  string   `json:"genre"`
+	Moods  []string `json:"moods,omitempty"`
+	Styles []string `json:"styles,omitempty"`
+}
+
+// ErrUnsupportedFormat is returned when tags are read or written in a container other than mp3.
+var ErrUnsupportedFormat = errors.New("only mp3 tags are supported")
+
+var parseFrames = []string{"Title", "Artist", "Album", "Genre", "User defined text information frame"}
+
+// IsAudioFile reports whether the file extension is one of the audio containers kept in the save dir.
+func IsAudioFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3", ".m4a", ".flac", ".ogg", ".opus":
+		return true
+	}
+	return false
+}
+
+// Read returns the tags stored in the file at path. Only ID3v2 tags in mp3 files are parsed, other
+// containers get their title from the file name so they still show up in listings.
+func Read(path string) (*Tags, error) {
+	if strings.ToLower(filepath.Ext(path)) != ".mp3" {
+		return &Tags{Title: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}, nil
+	}
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true, ParseFrames: parseFrames})
+	if err != nil {
+		return nil, err
+	}
+	defer tag.Close()
+	tags := &Tags{
+		Title:  tag.Title(),
+		Artist: tag.Artist(),
+		Album:  tag.Album(),
+		Genre:  tag.Genre(),
+	}
+	if tags.Title == "" {
+		tags.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	for _, frame := range tag.GetFrames(tag.CommonID("User defined text information frame")) {
+		udtf, ok := frame.(id3v2.UserDefinedTextFrame)
+		if !ok || udtf.Value == "" {
+			continue
+		}
+		switch udtf.Description {
+		case userTextFrames[FieldMood]:
+			tags.Moods = strings.Split(strings.TrimRight(udtf.Value, "\x00"), versionSeparator(tag))
+		case userTextFrames[FieldStyle]:
+			tags.Styles = strings.Split(strings.TrimRight(udtf.Value, "\x00"), versionSeparator(tag))
+		}
+	}
+	return tags, nil
+}
+
+// Fields are the tag values a retag compares and rewrites, keyed by field name. A missing or empty value means the
+// file has no such frame, a multi-valued field holds its values joined by ValueSeparator.
+type Fields map[string]string
+
+// ValueSeparator joins the values of a multi-valued field. It is also the separator written to ID3v2.3 tags, the
+// "/" some taggers use there splits names like "AC/DC".
+const ValueSeparator = "; "
+
+const (
+	FieldTitle                     = "title"
+	FieldArtist                    = "artist"
+	FieldArtists                   = "artists"
+	FieldAlbum                     = "album"
+	FieldAlbumArtist               = "album_artist"
+	FieldGenre                     = "genre"
+	FieldDate                      = "date"
+	FieldTrack                     = "track"
+	FieldDisc                      = "disc"
+	FieldComposer                  = "composer"
+	FieldISRC                      = "isrc"
+	FieldLength                    = "length"
+	FieldExplicit                  = "explicit"
+	FieldSpotifyTrackID            = "spotify_track_id"
+	FieldSpotifyAlbumID            = "spotify_album_id"
+	FieldSpotifyArtistID           = "spotify_artist_id"
+	FieldMusicBrainzRecordingID    = "musicbrainz_recording_id"
+	FieldMusicBrainzAlbumID        = "musicbrainz_album_id"
+	FieldMusicBrainzReleaseGroupID = "musicbrainz_release_group_id"
+	FieldMusicBrainzArtistID       = "musicbrainz_artist_id"
+
+	// the ReplayGain fields are measured from the audio, they are not part of a retag's diff
+	FieldReplayGainTrackGain = "replaygain_track_gain"
+	FieldReplayGainTrackPeak = "replaygain_track_peak"
+	FieldReplayGainAlbumGain = "replaygain_album_gain"
+	FieldReplayGainAlbumPeak = "replaygain_album_peak"
+
+	// the classifier's moods and styles, written by the Lambda and read into the library
+	FieldMood  = "mood"
+	FieldStyle = "style"
+)
+
+// FieldNames lists every field in the order diffs are shown in.
+var FieldNames = []string{
+	FieldTitle, FieldArtist, FieldArtists, FieldAlbum, FieldAlbumArtist, FieldGenre, FieldDate, FieldTrack, FieldDisc, FieldComposer,
+	FieldISRC, FieldLength, FieldExplicit, FieldSpotifyTrackID, FieldSpotifyAlbumID, FieldSpotifyArtistID,
+	FieldMusicBrainzRecordingID, FieldMusicBrainzAlbumID, FieldMusicBrainzReleaseGroupID, FieldMusicBrainzArtistID,
+}
+
+// textFrames maps fields to the common names of their text frames, the frame IDs depend on the tag version.
+var textFrames = map[string]string{
+	FieldTitle:       "Title",
+	FieldArtist:      "Artist",
+	FieldAlbum:       "Album/Movie/Show title",
+	FieldAlbumArtist: "Band/Orchestra/Accompaniment",
+	FieldGenre:       "Content type",
+	FieldDate:        "Year",
+	FieldTrack:       "Track number/Position in set",
+	FieldDisc:        "Part of a set",
+	FieldComposer:    "Composer",
+	FieldISRC:        "ISRC",
+	FieldLength:      "Length",
+}
+
+// userTextFrames maps fields to the descriptions of their TXXX frames, the same ones the Lambda writes.
+var userTextFrames = map[string]string{
+	FieldArtists:                   "ARTISTS",
+	FieldExplicit:                  "ITUNESADVISORY",
+	FieldSpotifyTrackID:            "SPOTIFY_TRACK_ID",
+	FieldSpotifyAlbumID:            "SPOTIFY_ALBUM_ID",
+	FieldSpotifyArtistID:           "SPOTIFY_ARTIST_ID",
+	FieldMusicBrainzAlbumID:        "MusicBrainz Album Id",
+	FieldMusicBrainzReleaseGroupID: "MusicBrainz Release Group Id",
+	FieldMusicBrainzArtistID:       "MusicBrainz Artist Id",
+	FieldReplayGainTrackGain:       "REPLAYGAIN_TRACK_GAIN",
+	FieldReplayGainTrackPeak:       "REPLAYGAIN_TRACK_PEAK",
+	FieldReplayGainAlbumGain:       "REPLAYGAIN_ALBUM_GAIN",
+	FieldReplayGainAlbumPeak:       "REPLAYGAIN_ALBUM_PEAK",
+	FieldMood:                      "MOOD",
+	FieldStyle:                     "STYLE",
+}
+
+// multiValueFields are the TXXX fields that hold several values, separated by a null byte in ID3v2.4 and by
+// ValueSeparator in ID3v2.3.
+var multiValueFields = map[string]bool{FieldArtists: true, FieldMood: true, FieldStyle: true}
+
+const musicBrainzUFIDOwner = "http://musicbrainz.org"
+
+// ReadFields returns every field stored in the ID3v2 tag of an mp3 file.
+func ReadFields(path string) (Fields, error) {
+	if strings.ToLower(filepath.Ext(path)) != ".mp3" {
+		return nil, ErrUnsupportedFormat
+	}
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tag.Close()
+	fields := make(Fields)
+	for field, name := range textFrames {
+		if value := tag.GetTextFrame(tag.CommonID(name)).Text; value != "" {
+			fields[field] = value
+		}
+	}
+	for _, frame := range tag.GetFrames(tag.CommonID("User defined text information frame")) {
+		udtf, ok := frame.(id3v2.UserDefinedTextFrame)
+		if !ok {
+			continue
+		}
+		for field, description := range userTextFrames {
+			if udtf.Description == description && udtf.Value != "" {
+				fields[field] = udtf.Value
+				if multiValueFields[field] {
+					fields[field] = strings.Join(strings.Split(strings.TrimRight(udtf.Value, "\x00"), versionSeparator(tag)), ValueSeparator)
+				}
+			}
+		}
+	}
+	for _, frame := range tag.GetFrames(tag.CommonID("Unique file identifier")) {
+		if ufid, ok := frame.(id3v2.UFIDFrame); ok && ufid.OwnerIdentifier == musicBrainzUFIDOwner {
+			fields[FieldMusicBrainzRecordingID] = string(ufid.Identifier)
+		}
+	}
+	return fields, nil
+}
+
+// WriteFields rewrites the given fields in the ID3v2 tag of an mp3 file in place, the audio is copied untouched.
+// Fields not in the map are left as they are and an empty value removes the frame. A non-nil cover replaces the
+// front cover picture.
+func WriteFields(path string, fields Fields, cover *Picture) error {
+	if strings.ToLower(filepath.Ext(path)) != ".mp3" {
+		return ErrUnsupportedFormat
+	}
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return err
+	}
+	defer tag.Close()
+	tag.SetDefaultEncoding(id3v2.EncodingUTF8)
+	for field, value := range fields {
+		if name, ok := textFrames[field]; ok {
+			tag.DeleteFrames(tag.CommonID(name))
+			if value != "" {
+				tag.AddTextFrame(tag.CommonID(name), tag.DefaultEncoding(), value)
+			}
+		} else if description, ok := userTextFrames[field]; ok {
+			if multiValueFields[field] {
+				value = strings.ReplaceAll(value, ValueSeparator, versionSeparator(tag))
+			}
+			setUserText(tag, description, value)
+		} else if field == FieldMusicBrainzRecordingID {
+			tag.DeleteFrames(tag.CommonID("Unique file identifier"))
+			if value != "" {
+				tag.AddUFIDFrame(id3v2.UFIDFrame{OwnerIdentifier: musicBrainzUFIDOwner, Identifier: []byte(value)})
+			}
+		}
+	}
+	if cover != nil {
+		tag.DeleteFrames(tag.CommonID("Attached picture"))
+		tag.AddAttachedPicture(id3v2.PictureFrame{
+			Encoding:    id3v2.EncodingUTF8,
+			MimeType:    cover.MimeType,
+			PictureType: id3v2.PTFrontCover,
+			Description: "Front cover",
+			Picture:     cover.Data,
+		})
+	}
+	return tag.Save()
+}
+
+// Picture is an image to embed as the front cover.
+type Picture struct {
+	MimeType string
+	Data     []byte
+}
+
+// versionSeparator returns the separator between the values of a multi-valued frame in the tag's version.
+func versionSeparator(tag *id3v2.Tag) string {
+	if tag.Version() == 4 {
+		return "\x00"
+	}
+	return ValueSeparator
+}
+
+// setUserText replaces the TXXX frame with the given description, an empty value only removes it.
+func setUserText(tag *id3v2.Tag, description string, value string) {
+	frames := tag.GetFrames(tag.CommonID("User defined text information frame"))
+	tag.DeleteFrames(tag.CommonID("User defined text information frame"))
+	for _, frame := range frames {
+		if udtf, ok := frame.(id3v2.UserDefinedTextFrame); ok && udtf.Description != description {
+			tag.AddUserDefinedTextFrame(udtf)
+		}
+	}
+	if value == "" {
+		return
+	}
+	tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
+		Encoding:    id3v2.EncodingUTF8,
+		Description: description,
+		Value:       value,
+	})
+}
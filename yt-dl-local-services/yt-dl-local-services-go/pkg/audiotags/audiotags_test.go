@@ -0,0 +1,59 @@
+package audiotags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bogem/id3v2/v2"
+)
+
+func TestMultiValueFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		version byte
+		stored  string
+	}{
+		{"ID3v2.3", 3, "AC/DC; Bon Scott"},
+		{"ID3v2.4", 4, "AC/DC\x00Bon Scott"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "track.mp3")
+		tag := id3v2.NewEmptyTag()
+		tag.SetVersion(tt.version)
+		tag.SetTitle("Highway to Hell")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tag.WriteTo(file); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+
+		fields := Fields{FieldArtists: "AC/DC" + ValueSeparator + "Bon Scott", FieldMood: "Energetic"}
+		if err := WriteFields(path, fields, nil); err != nil {
+			t.Fatalf("%s: WriteFields() returned %v", tt.name, err)
+		}
+		written, err := id3v2.Open(path, id3v2.Options{Parse: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, frame := range written.GetFrames(written.CommonID("User defined text information frame")) {
+			if udtf := frame.(id3v2.UserDefinedTextFrame); udtf.Description == "ARTISTS" && udtf.Value != tt.stored {
+				t.Errorf("%s: ARTISTS frame %q, want %q", tt.name, udtf.Value, tt.stored)
+			}
+		}
+		written.Close()
+
+		got, err := ReadFields(path)
+		if err != nil {
+			t.Fatalf("%s: ReadFields() returned %v", tt.name, err)
+		}
+		for field, want := range fields {
+			if got[field] != want {
+				t.Errorf("%s: ReadFields()[%q] = %q, want %q", tt.name, field, got[field], want)
+			}
+		}
+	}
+}
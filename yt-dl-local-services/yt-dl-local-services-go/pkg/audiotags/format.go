@@ -0,0 +1,92 @@
+package audiotags
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format describes the encoding of an audio file. Codec details are only read from mp3 files, other containers
+// only report their size.
+type Format struct {
+	Container   string `json:"container"`
+	Codec       string `json:"codec,omitempty"`
+	BitrateKbps int    `json:"bitrate_kbps,omitempty"`
+	SampleRate  int    `json:"sample_rate,omitempty"`
+	Channels    int    `json:"channels,omitempty"`
+	DurationMs  int    `json:"duration_ms,omitempty"`
+	SizeBytes   int64  `json:"size_bytes"`
+}
+
+var errNoFrame = errors.New("no mpeg audio frame found")
+
+// mp3HeaderScan bounds how far past the ID3 tag the first frame header is looked for
+const mp3HeaderScan = 64 * 1024
+
+var (
+	mpeg1Layer3Bitrates = []int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	mpeg2Layer3Bitrates = []int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+	// sampleRates are indexed by the version bits of the frame header, 1 is reserved
+	sampleRates = map[byte][]int{0: {11025, 12000, 8000}, 2: {22050, 24000, 16000}, 3: {44100, 48000, 32000}}
+)
+
+// ReadFormat returns the container and size of an audio file, and for mp3 files the codec details of the first
+// frame. The duration assumes a constant bitrate, which is what the Lambda encodes.
+func ReadFormat(path string) (*Format, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	format := &Format{Container: strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."), SizeBytes: info.Size()}
+	if format.Container != "mp3" {
+		return format, nil
+	}
+	head := make([]byte, 10)
+	if _, err := io.ReadFull(file, head); err != nil {
+		return nil, err
+	}
+	offset := int64(0)
+	if string(head[:3]) == "ID3" {
+		offset = int64(head[6]&0x7f)<<21 | int64(head[7]&0x7f)<<14 | int64(head[8]&0x7f)<<7 | int64(head[9]&0x7f) + 10
+		if head[5]&0x10 != 0 {
+			offset += 10
+		}
+	}
+	buf := make([]byte, mp3HeaderScan)
+	n, err := file.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	for i := 0; i+4 <= n; i++ {
+		if buf[i] != 0xff || buf[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		version, layer := buf[i+1]>>3&3, buf[i+1]>>1&3
+		bitrateIndex, rateIndex := int(buf[i+2]>>4), int(buf[i+2]>>2&3)
+		if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+			continue
+		}
+		format.Codec = "mp3"
+		if version == 3 {
+			format.BitrateKbps = mpeg1Layer3Bitrates[bitrateIndex]
+		} else {
+			format.BitrateKbps = mpeg2Layer3Bitrates[bitrateIndex]
+		}
+		format.SampleRate = sampleRates[version][rateIndex]
+		format.Channels = 2
+		if buf[i+3]>>6 == 3 {
+			format.Channels = 1
+		}
+		audioBytes := format.SizeBytes - offset - int64(i)
+		format.DurationMs = int(audioBytes * 8 / int64(format.BitrateKbps))
+		return format, nil
+	}
+	return nil, errNoFrame
+}
@@ -0,0 +1,264 @@
+package fingerprint
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"math/cmplx"
+	"sort"
+)
+
+const (
+	// SampleRate is the rate of the mono 16 bit PCM Compute expects, ffmpeg resamples to it with "-ac 1 -ar 5512"
+	SampleRate = 5512
+	// MaxSeconds is how much of the start of a track is fingerprinted, enough to get past a music video's intro
+	MaxSeconds = 120
+
+	// frameSize samples are analysed every hopSize samples, about 370ms windows every 93ms
+	frameSize = 2048
+	hopSize   = 512
+	// the spectrum between minFreq and maxFreq is split into 33 bands, each sub-fingerprint holds for the 32 pairs
+	// of neighbouring bands whether their loudness difference is above its median over the track, which leaves out
+	// the volume and equalisation of an upload
+	bandCount = 33
+	minFreq   = 300
+	maxFreq   = 2000
+
+	// minOverlap is the fewest sub-fingerprints two fingerprints must share to be compared, about 10 seconds
+	minOverlap = 100
+	// probeStep is how many sub-fingerprints apart a looked up fingerprint is sampled, an aligned match collects
+	// votes all along its length so a sample is enough
+	probeStep = 4
+	// minVotes is how many matching half sub-fingerprints at the same offset make an indexed fingerprint worth
+	// comparing
+	minVotes = 4
+	// candidateOffsets is how many of the best voted offsets are compared
+	candidateOffsets = 3
+)
+
+var ErrInvalid = errors.New("invalid fingerprint")
+
+// Fingerprint is a sequence of 32 bit sub-fingerprints, one per analysis frame. Two encodings of the same audio,
+// a music video and the official audio, give fingerprints that differ in few bits once aligned.
+type Fingerprint []uint32
+
+// Compute fingerprints mono PCM samples at SampleRate, only the first MaxSeconds are used.
+func Compute(samples []int16) Fingerprint {
+	samples = samples[:min(len(samples), SampleRate*MaxSeconds)]
+	window := make([]float64, frameSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frameSize-1))
+	}
+	edges := make([]int, bandCount+1)
+	for i := range edges {
+		freq := minFreq * math.Pow(float64(maxFreq)/minFreq, float64(i)/bandCount)
+		edges[i] = int(freq * frameSize / SampleRate)
+	}
+
+	frames := make([][]float64, 0, max(0, (len(samples)-frameSize)/hopSize+1))
+	spectrum := make([]complex128, frameSize)
+	for start := 0; start+frameSize <= len(samples); start += hopSize {
+		for i := range spectrum {
+			spectrum[i] = complex(float64(samples[start+i])/math.MaxInt16*window[i], 0)
+		}
+		fft(spectrum)
+		differences := make([]float64, bandCount-1)
+		previous := 0.0
+		for band := 0; band < bandCount; band++ {
+			energy := 0.0
+			for bin := edges[band]; bin < max(edges[band+1], edges[band]+1); bin++ {
+				energy += real(spectrum[bin])*real(spectrum[bin]) + imag(spectrum[bin])*imag(spectrum[bin])
+			}
+			// the small floor keeps digital silence finite
+			loudness := math.Log10(energy + 1e-10)
+			if band > 0 {
+				differences[band-1] = previous - loudness
+			}
+			previous = loudness
+		}
+		frames = append(frames, differences)
+	}
+
+	medians := make([]float64, bandCount-1)
+	column := make([]float64, len(frames))
+	for pair := range medians {
+		for i, frame := range frames {
+			column[i] = frame[pair]
+		}
+		sort.Float64s(column)
+		if len(column) > 0 {
+			medians[pair] = column[len(column)/2]
+		}
+	}
+	fp := make(Fingerprint, len(frames))
+	for i, frame := range frames {
+		for pair, difference := range frame {
+			if difference > medians[pair] {
+				fp[i] |= 1 << pair
+			}
+		}
+	}
+	return fp
+}
+
+// String encodes the fingerprint as base64, four little endian bytes per sub-fingerprint.
+func (f Fingerprint) String() string {
+	data := make([]byte, 4*len(f))
+	for i, sub := range f {
+		binary.LittleEndian.PutUint32(data[4*i:], sub)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// Decode reads a fingerprint written by String.
+func Decode(str string) (Fingerprint, error) {
+	data, err := base64.StdEncoding.DecodeString(str)
+	if err != nil || len(data)%4 != 0 {
+		return nil, ErrInvalid
+	}
+	fp := make(Fingerprint, len(data)/4)
+	for i := range fp {
+		fp[i] = binary.LittleEndian.Uint32(data[4*i:])
+	}
+	return fp, nil
+}
+
+// Similarity returns the share of matching bits between two fingerprints at the offset that aligns them best, 1
+// for identical audio and around 0.5 for unrelated audio.
+func Similarity(a Fingerprint, b Fingerprint) float64 {
+	index := NewIndex()
+	index.Add("", b)
+	if matches := index.Matches(a, 0); len(matches) > 0 {
+		return matches[0].Similarity
+	}
+	return 0
+}
+
+// Index finds the fingerprints that match a given one without comparing it to every fingerprint at every offset:
+// fingerprints sharing halves of sub-fingerprints vote for the offset they share them at, and only the best
+// offsets are compared. Halves are used because a whole sub-fingerprint of a noisy copy rarely matches exactly.
+type Index struct {
+	keys     []string
+	prints   []Fingerprint
+	postings map[uint32][]posting
+}
+
+type posting struct {
+	entry    int
+	position int
+}
+
+// Match is an indexed fingerprint and how similar it is to the one looked up.
+type Match struct {
+	Key        string
+	Similarity float64
+}
+
+func NewIndex() *Index {
+	return &Index{postings: make(map[uint32][]posting)}
+}
+
+// Add indexes a fingerprint under a key.
+func (x *Index) Add(key string, fp Fingerprint) {
+	entry := len(x.prints)
+	x.keys = append(x.keys, key)
+	x.prints = append(x.prints, fp)
+	for position, sub := range fp {
+		for _, half := range halves(sub) {
+			x.postings[half] = append(x.postings[half], posting{entry: entry, position: position})
+		}
+	}
+}
+
+// Matches returns the indexed fingerprints at least threshold similar to fp, the most similar first.
+func (x *Index) Matches(fp Fingerprint, threshold float64) []Match {
+	votes := make(map[int]map[int]int)
+	for position := 0; position < len(fp); position += probeStep {
+		for _, half := range halves(fp[position]) {
+			for _, p := range x.postings[half] {
+				if votes[p.entry] == nil {
+					votes[p.entry] = make(map[int]int)
+				}
+				votes[p.entry][p.position-position]++
+			}
+		}
+	}
+	matches := make([]Match, 0)
+	for entry, offsets := range votes {
+		best := 0.0
+		for _, offset := range topOffsets(offsets) {
+			for shift := -1; shift <= 1; shift++ {
+				best = max(best, similarityAt(fp, x.prints[entry], offset+shift))
+			}
+		}
+		if best > 0 && best >= threshold {
+			matches = append(matches, Match{Key: x.keys[entry], Similarity: best})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	return matches
+}
+
+// halves splits a sub-fingerprint into its two 16 bit halves, the high half is marked so the two never collide.
+func halves(sub uint32) [2]uint32 {
+	return [2]uint32{sub & 0xffff, sub>>16 | 1<<16}
+}
+
+// topOffsets returns the offsets with the most votes, ignoring those with fewer than minVotes.
+func topOffsets(votes map[int]int) []int {
+	offsets := make([]int, 0, len(votes))
+	for offset, count := range votes {
+		if count >= minVotes {
+			offsets = append(offsets, offset)
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		if votes[offsets[i]] != votes[offsets[j]] {
+			return votes[offsets[i]] > votes[offsets[j]]
+		}
+		return offsets[i] < offsets[j]
+	})
+	return offsets[:min(len(offsets), candidateOffsets)]
+}
+
+// similarityAt compares a with b shifted by offset sub-fingerprints, b[i+offset] against a[i]. Fingerprints that
+// overlap by less than minOverlap are not similar.
+func similarityAt(a Fingerprint, b Fingerprint, offset int) float64 {
+	start, end := max(0, -offset), min(len(a), len(b)-offset)
+	if end-start < minOverlap {
+		return 0
+	}
+	differing := 0
+	for i := start; i < end; i++ {
+		differing += bits.OnesCount32(a[i] ^ b[i+offset])
+	}
+	return 1 - float64(differing)/float64(32*(end-start))
+}
+
+// fft is an in place radix 2 Cooley-Tukey transform, len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = even+odd, even-odd
+				w *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package pathtemplate
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Fields are the placeholders a template may reference, e.g. {album_artist}/{album}/{track:02} - {title}.{ext}
+var Fields = []string{"id", "title", "artist", "album_artist", "album", "genre", "year", "track", "track_total", "disc", "ext"}
+
+const (
+	// maxComponentBytes keeps every folder and file name well under the 255 byte limit of common file systems
+	// and leaves room for a collision suffix.
+	maxComponentBytes = 180
+	// maxPathBytes bounds the path relative to the save dir so the absolute path stays under the Windows
+	// MAX_PATH limit for reasonably short save dirs.
+	maxPathBytes = 220
+	minStemBytes = 16
+	maxExtBytes  = 6
+	separators   = " -_"
+)
+
+var placeholderRegex = regexp.MustCompile(`\{([a-z_]+)(?::(0?)(\d+))?\}`)
+var invalidCharsRegex = regexp.MustCompile(`[\\/:*?"<>|\x00-\x1F\x7F]`)
+var reservedNameRegex = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[0-9]|lpt[0-9])(\..*)?$`)
+
+// Validate checks that the template only uses known placeholders, ends with {ext} and cannot escape the save dir.
+func Validate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("template is empty")
+	}
+	if strings.HasPrefix(template, "/") || strings.HasPrefix(template, `\`) || strings.Contains(template, ":/") {
+		return fmt.Errorf("template must be relative to the save dir")
+	}
+	for _, component := range strings.Split(template, "/") {
+		if component == ".." || component == "." {
+			return fmt.Errorf("template must not contain %q path components", component)
+		}
+	}
+	for _, match := range placeholderRegex.FindAllStringSubmatch(template, -1) {
+		if !isField(match[1]) {
+			return fmt.Errorf("unknown template field {%s}", match[1])
+		}
+	}
+	if !strings.HasSuffix(template, "{ext}") {
+		return fmt.Errorf("template must end with the {ext} field")
+	}
+	return nil
+}
+
+// Render fills the template with values and returns a slash separated path relative to the save dir. Every
+// value is sanitized before it is substituted so a "/" in a title never creates a folder, and every resulting
+// component is made safe for Windows, macOS and Linux file systems.
+func Render(template string, values map[string]string) (string, error) {
+	if err := Validate(template); err != nil {
+		return "", err
+	}
+	components := strings.Split(template, "/")
+	for i, component := range components {
+		components[i] = SanitizeComponent(renderComponent(component, values))
+	}
+	return fitPath(components, ""), nil
+}
+
+// renderComponent substitutes the placeholders of a single path component. When a placeholder has no value the
+// separator next to it is dropped as well, so "{track:02} - {title}" renders as "Title" rather than " - Title".
+func renderComponent(component string, values map[string]string) string {
+	var out strings.Builder
+	matches := placeholderRegex.FindAllStringSubmatchIndex(component, -1)
+	last := 0
+	skipSeparator := false
+	for _, m := range matches {
+		literal := component[last:m[0]]
+		if skipSeparator {
+			literal = strings.TrimLeft(literal, separators)
+		}
+		out.WriteString(literal)
+		last = m[1]
+		name := component[m[2]:m[3]]
+		var zeroPad, width string
+		if m[4] >= 0 {
+			zeroPad, width = component[m[4]:m[5]], component[m[6]:m[7]]
+		}
+		value := format(sanitizeValue(values[name]), zeroPad, width)
+		if value == "" {
+			trimmed := strings.TrimRight(out.String(), separators)
+			out.Reset()
+			out.WriteString(trimmed)
+			skipSeparator = trimmed == ""
+			continue
+		}
+		skipSeparator = false
+		out.WriteString(value)
+	}
+	literal := component[last:]
+	if skipSeparator {
+		literal = strings.TrimLeft(literal, separators)
+	}
+	out.WriteString(literal)
+	return out.String()
+}
+
+// SanitizeComponent makes a single folder or file name safe on all platforms: characters that are invalid on
+// Windows are replaced, leading spaces and trailing dots and spaces are trimmed, reserved device names are
+// prefixed and the name is truncated to a safe length without splitting a UTF-8 sequence.
+func SanitizeComponent(name string) string {
+	name = sanitizeValue(name)
+	name = strings.TrimRight(name, " .")
+	if reservedNameRegex.MatchString(name) {
+		name = "_" + name
+	}
+	if len(name) > maxComponentBytes {
+		ext := path.Ext(name)
+		if len(ext) > maxExtBytes {
+			ext = ""
+		}
+		name = strings.TrimRight(truncate(strings.TrimSuffix(name, ext), maxComponentBytes-len(ext)), " .") + ext
+	}
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+func sanitizeValue(value string) string {
+	return strings.TrimSpace(invalidCharsRegex.ReplaceAllString(value, "_"))
+}
+
+// WithSuffix inserts suffix before the extension of a rendered path, it is used to make two different tracks
+// that render to the same path distinct. The file name is shortened to make room for the suffix, so the path
+// stays within the same limits as a rendered one.
+func WithSuffix(rendered string, suffix string) string {
+	components := strings.Split(rendered, "/")
+	last := len(components) - 1
+	ext := path.Ext(components[last])
+	stem := strings.TrimSuffix(components[last], ext)
+	if over := len(stem) + len(suffix) + len(ext) - maxComponentBytes; over > 0 {
+		stem = strings.TrimRight(truncate(stem, max(0, len(stem)-over)), " .")
+	}
+	components[last] = stem + ext
+	return fitPath(components, suffix)
+}
+
+func format(value string, zeroPad string, width string) string {
+	if width == "" || value == "" {
+		return value
+	}
+	n, err := strconv.Atoi(value)
+	w, werr := strconv.Atoi(width)
+	if err != nil || werr != nil {
+		return value
+	}
+	if zeroPad == "0" {
+		return fmt.Sprintf("%0*d", w, n)
+	}
+	return fmt.Sprintf("%*d", w, n)
+}
+
+// fitPath shortens the longest components until the whole path fits in maxPathBytes. The suffix is added to the
+// file name before its extension, neither of them is ever truncated.
+func fitPath(components []string, suffix string) string {
+	last := len(components) - 1
+	ext := path.Ext(components[last])
+	components[last] = strings.TrimSuffix(components[last], ext)
+	tail := suffix + ext
+	total := func() int {
+		n := len(tail) + len(components) - 1
+		for _, c := range components {
+			n += len(c)
+		}
+		return n
+	}
+	for total() > maxPathBytes {
+		longest := 0
+		for i, c := range components {
+			if len(c) > len(components[longest]) {
+				longest = i
+			}
+		}
+		if len(components[longest]) <= minStemBytes {
+			break
+		}
+		target := max(minStemBytes, len(components[longest])-(total()-maxPathBytes))
+		components[longest] = strings.TrimRight(truncate(components[longest], target), " .")
+	}
+	components[last] += tail
+	return strings.Join(components, "/")
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	s = s[:limit]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package pathtemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithSuffix(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	tests := []struct {
+		name     string
+		template string
+		values   map[string]string
+	}{
+		{"short", "{artist} - {title}.{ext}", map[string]string{"artist": "A", "title": "Song", "ext": "mp3"}},
+		{"long title", "{artist} - {title}.{ext}", map[string]string{"artist": "A", "title": long, "ext": "mp3"}},
+		{"long folders", "{album_artist}/{album}/{title}.{ext}", map[string]string{"album_artist": long, "album": long, "title": long, "ext": "mp3"}},
+	}
+	for _, tt := range tests {
+		rendered, err := Render(tt.template, tt.values)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := WithSuffix(rendered, " [dQw4w9WgXcQ]")
+		components := strings.Split(got, "/")
+		name := components[len(components)-1]
+		if !strings.HasSuffix(got, " [dQw4w9WgXcQ].mp3") {
+			t.Errorf("%s: %q does not end with the suffix and extension", tt.name, got)
+		}
+		if len(got) > maxPathBytes || len(name) > maxComponentBytes {
+			t.Errorf("%s: %q is %d bytes with a %d byte name, over the limits", tt.name, got, len(got), len(name))
+		}
+		if len(components) != len(strings.Split(rendered, "/")) {
+			t.Errorf("%s: %q changed the folders of %q", tt.name, got, rendered)
+		}
+	}
+	if got := WithSuffix("A - Song.mp3", " [id]"); got != "A - Song [id].mp3" {
+		t.Errorf("WithSuffix = %q, want %q", got, "A - Song [id].mp3")
+	}
+}
@@ -0,0 +1,189 @@
+package qualifiers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/textnorm"
+)
+
+// Version describes which recording of a song a title names. The zero value is the original studio version.
+type Version struct {
+	Remix        bool   `json:"remix,omitempty"`
+	Remixer      string `json:"remixer,omitempty"`
+	Live         bool   `json:"live,omitempty"`
+	Acoustic     bool   `json:"acoustic,omitempty"`
+	Instrumental bool   `json:"instrumental,omitempty"`
+	Remastered   bool   `json:"remastered,omitempty"`
+	RemasterYear int    `json:"remaster_year,omitempty"`
+	SpedUp       bool   `json:"sped_up,omitempty"`
+	Slowed       bool   `json:"slowed,omitempty"`
+}
+
+// Title is a title split into its base and the bracketed or dash-suffixed parts worth keeping. Qualifiers holds
+// the version and featured artist parts in the order they appeared, everything else like "(Official Video)" or
+// "[HD]" is dropped.
+type Title struct {
+	Base       string   `json:"base"`
+	Qualifiers []string `json:"qualifiers,omitempty"`
+	Version    Version  `json:"version"`
+}
+
+var (
+	bracketed = regexp.MustCompile(`\(([^()]*)\)|\[([^\[\]]*)\]|【([^【】]*)】`)
+	// dashSuffix is the last " - part" of a title, it only counts as a qualifier when the whole part is one, so
+	// "Oasis - Live Forever" keeps its title
+	dashSuffix = regexp.MustCompile(`\s+[-–—]\s+([^-–—]+)$`)
+
+	remixWords       = regexp.MustCompile(`(?i)\b(?:remix|rmx|bootleg|flip|rework)\b`)
+	remixedBy        = regexp.MustCompile(`(?i)\b(?:remixed|reworked)\s+by\s+(.+)$`)
+	remixerBefore    = regexp.MustCompile(`(?i)^(.*?)\s*(?:'s\s+)?\b(?:remix|rmx|bootleg|flip|rework)\b`)
+	liveWords        = regexp.MustCompile(`(?i)\b(?:live|unplugged)\b`)
+	acousticWords    = regexp.MustCompile(`(?i)\b(?:acoustic|unplugged)\b`)
+	instrumentalWord = regexp.MustCompile(`(?i)\binstrumental\b`)
+	remasterWords    = regexp.MustCompile(`(?i)\bremaster(?:ed)?\b`)
+	year             = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+	spedUpWords      = regexp.MustCompile(`(?i)\b(?:sped\s*up|speed\s*up|nightcore)\b`)
+	slowedWords      = regexp.MustCompile(`(?i)\bslowed\b`)
+	featWords        = regexp.MustCompile(`(?i)^(?:feat\.?|ft\.?|featuring|with)\s+`)
+	// junkWords are dropped from a kept qualifier, "(Official Live Video)" is kept as "Live"
+	junkWords = regexp.MustCompile(`(?i)\b(?:official|music|lyrics?|video|audio|visuali[sz]er|hd|hq|4k|mv)\b`)
+
+	// strictSuffix lists the dash suffixes taken as qualifiers, the remix form needs a word before "remix" or the
+	// word alone, the live form "live" alone or followed by where it was recorded
+	strictSuffix = regexp.MustCompile(`(?i)^(?:` +
+		`(?:.+\s+)?(?:remix|rmx|bootleg|flip|rework)|remixed by .+|` +
+		`live|live (?:at|from|in|on) .+|unplugged|` +
+		`acoustic(?: version| session)?|instrumental(?: version)?|` +
+		`(?:(?:19|20)\d{2} )?remaster(?:ed)?(?: (?:19|20)\d{2})?(?: version)?|` +
+		`sped up(?: version)?|slowed(?: \+ reverb| and reverb| & reverb)?` +
+		`)$`)
+)
+
+// Parse splits a YouTube or provider title into its base, the qualifiers to keep and the version they describe.
+func Parse(title string) Title {
+	out := Title{Qualifiers: make([]string, 0)}
+	for _, match := range bracketed.FindAllStringSubmatch(title, -1) {
+		part := strings.TrimSpace(match[1] + match[2] + match[3])
+		if featWords.MatchString(part) {
+			out.Qualifiers = append(out.Qualifiers, part)
+		} else if version, ok := detect(part); ok {
+			if part = strings.Join(strings.Fields(junkWords.ReplaceAllString(part, "")), " "); part != "" {
+				out.Qualifiers = append(out.Qualifiers, part)
+			}
+			out.Version.Merge(version)
+		}
+	}
+	base := strings.TrimSpace(bracketed.ReplaceAllString(title, " "))
+	for {
+		match := dashSuffix.FindStringSubmatchIndex(base)
+		if match == nil {
+			break
+		}
+		part := strings.TrimSpace(base[match[2]:match[3]])
+		if !strictSuffix.MatchString(part) {
+			break
+		}
+		version, _ := detect(part)
+		out.Qualifiers = append([]string{part}, out.Qualifiers...)
+		out.Version.Merge(version)
+		base = strings.TrimSpace(base[:match[0]])
+	}
+	out.Base = strings.Join(strings.Fields(base), " ")
+	if remixWords.MatchString(out.Base) {
+		// an unbracketed "Song Remix" stays in the base but still names a remix
+		out.Version.Remix = true
+	}
+	return out
+}
+
+// Detect returns the version markers found anywhere in a text, such as an album title.
+func Detect(text string) Version {
+	version, _ := detect(text)
+	return version
+}
+
+// String writes the title back with every kept qualifier in brackets, "Song (Skrillex Remix) (Live)".
+func (t Title) String() string {
+	str := t.Base
+	for _, qualifier := range t.Qualifiers {
+		str += " (" + qualifier + ")"
+	}
+	return str
+}
+
+// IsZero reports whether the version is the original recording without any markers.
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Merge adds the markers of another version, a remixer or remaster year already known is kept.
+func (v *Version) Merge(other Version) {
+	v.Remix = v.Remix || other.Remix
+	if v.Remixer == "" {
+		v.Remixer = other.Remixer
+	}
+	v.Live = v.Live || other.Live
+	v.Acoustic = v.Acoustic || other.Acoustic
+	v.Instrumental = v.Instrumental || other.Instrumental
+	v.Remastered = v.Remastered || other.Remastered
+	if v.RemasterYear == 0 {
+		v.RemasterYear = other.RemasterYear
+	}
+	v.SpedUp = v.SpedUp || other.SpedUp
+	v.Slowed = v.Slowed || other.Slowed
+}
+
+// Mismatches lists the version markers that only one of two versions carries, a remixer only counts when both
+// name one.
+func (v Version) Mismatches(other Version) []string {
+	mismatches := make([]string, 0)
+	if v.Remix != other.Remix {
+		mismatches = append(mismatches, "remix")
+	} else if v.Remixer != "" && other.Remixer != "" && !sameRemixer(v.Remixer, other.Remixer) {
+		mismatches = append(mismatches, "remixer")
+	}
+	if v.Live != other.Live {
+		mismatches = append(mismatches, "live")
+	}
+	if v.Acoustic != other.Acoustic {
+		mismatches = append(mismatches, "acoustic")
+	}
+	if v.Instrumental != other.Instrumental {
+		mismatches = append(mismatches, "instrumental")
+	}
+	if v.SpedUp != other.SpedUp || v.Slowed != other.Slowed {
+		mismatches = append(mismatches, "speed")
+	}
+	return mismatches
+}
+
+func detect(text string) (Version, bool) {
+	var v Version
+	if remixWords.MatchString(text) || remixedBy.MatchString(text) {
+		v.Remix = true
+		if match := remixedBy.FindStringSubmatch(text); match != nil {
+			v.Remixer = strings.TrimSpace(match[1])
+		} else if match := remixerBefore.FindStringSubmatch(text); match != nil {
+			v.Remixer = strings.TrimSpace(match[1])
+		}
+	}
+	v.Live = liveWords.MatchString(text)
+	v.Acoustic = acousticWords.MatchString(text)
+	v.Instrumental = instrumentalWord.MatchString(text)
+	if remasterWords.MatchString(text) {
+		v.Remastered = true
+		if match := year.FindString(text); match != "" {
+			v.RemasterYear, _ = strconv.Atoi(match)
+		}
+	}
+	v.SpedUp = spedUpWords.MatchString(text)
+	v.Slowed = slowedWords.MatchString(text)
+	return v, v != Version{}
+}
+
+func sameRemixer(a string, b string) bool {
+	a, b = textnorm.Fold(a), textnorm.Fold(b)
+	return strings.Contains(a, b) || strings.Contains(b, a)
+}
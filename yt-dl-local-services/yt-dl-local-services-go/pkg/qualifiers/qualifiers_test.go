@@ -0,0 +1,61 @@
+package qualifiers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		title   string
+		want    string
+		version Version
+	}{
+		{"Song (Official Video)", "Song", Version{}},
+		{"Song 【MV】", "Song", Version{}},
+		{"Song (Skrillex Remix) [Official Audio]", "Song (Skrillex Remix)", Version{Remix: true, Remixer: "Skrillex"}},
+		{"Song - Skrillex Remix", "Song (Skrillex Remix)", Version{Remix: true, Remixer: "Skrillex"}},
+		{"Song (Remixed by DJ X)", "Song (Remixed by DJ X)", Version{Remix: true, Remixer: "DJ X"}},
+		{"Oasis - Live Forever", "Oasis - Live Forever", Version{}},
+		{"Song (Live at Wembley)", "Song (Live at Wembley)", Version{Live: true}},
+		{"Song - Live", "Song (Live)", Version{Live: true}},
+		{"Song (MTV Unplugged)", "Song (MTV Unplugged)", Version{Live: true, Acoustic: true}},
+		{"Song (Acoustic Version)", "Song (Acoustic Version)", Version{Acoustic: true}},
+		{"Song (feat. A) (Remastered 2011)", "Song (feat. A) (Remastered 2011)", Version{Remastered: true, RemasterYear: 2011}},
+		{"Song - 2011 Remaster", "Song (2011 Remaster)", Version{Remastered: true, RemasterYear: 2011}},
+		{"Song (Sped Up)", "Song (Sped Up)", Version{SpedUp: true}},
+		{"Song (slowed + reverb)", "Song (slowed + reverb)", Version{Slowed: true}},
+	}
+	for _, tt := range tests {
+		got := Parse(tt.title)
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.title, got.String(), tt.want)
+		}
+		if got.Version != tt.version {
+			t.Errorf("Parse(%q).Version = %+v, want %+v", tt.title, got.Version, tt.version)
+		}
+	}
+}
+
+func TestVersionMismatches(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Version
+		want []string
+	}{
+		{"original", Version{}, Version{}, []string{}},
+		{"remix and original", Version{Remix: true}, Version{}, []string{"remix"}},
+		{"other remixer", Version{Remix: true, Remixer: "Skrillex"}, Version{Remix: true, Remixer: "Diplo"}, []string{"remixer"}},
+		{"remixer case", Version{Remix: true, Remixer: "Skrillex"}, Version{Remix: true, Remixer: "SKRILLEX"}, []string{}},
+		{"unknown remixer", Version{Remix: true, Remixer: "Skrillex"}, Version{Remix: true}, []string{}},
+		{"live and studio", Version{}, Version{Live: true}, []string{"live"}},
+		{"remaster only", Version{Remastered: true, RemasterYear: 2011}, Version{}, []string{}},
+		{"speed", Version{SpedUp: true}, Version{Slowed: true}, []string{"speed"}},
+		{"several", Version{Live: true, Acoustic: true}, Version{Remix: true}, []string{"remix", "live", "acoustic"}},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Mismatches(tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Mismatches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package textnorm
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// letterFolds are the Latin letters without a canonical decomposition that still read as a base letter plus a
+// diacritic, or as a ligature of two letters.
+var letterFolds = map[rune]string{
+	'ø': "o",
+	'æ': "ae",
+	'œ': "oe",
+	'ł': "l",
+	'đ': "d",
+	'ð': "d",
+	'þ': "th",
+	'ı': "i",
+}
+
+var (
+	unsafeChars = regexp.MustCompile(`[^\p{L}\p{M}\p{N}\s:\-]`)
+	spaces      = regexp.MustCompile(`\s+`)
+)
+
+// Fold reduces a string to the form titles and artists are compared in. NFKC turns full-width letters and digits
+// into their ASCII forms and compatibility characters into plain ones, the result is case folded and the accents
+// of Latin, Greek and Cyrillic letters are dropped. Other scripts keep their marks, a Japanese dakuten changes the
+// sound and must not be removed. Fold is for comparison only, it is never written to tags or file names.
+func Fold(str string) string {
+	str = cases.Fold().String(norm.NFKC.String(str))
+	var b strings.Builder
+	strip := false
+	for _, r := range norm.NFD.String(str) {
+		if unicode.Is(unicode.Mn, r) {
+			if !strip {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		strip = unicode.In(r, unicode.Latin, unicode.Greek, unicode.Cyrillic)
+		if folded, ok := letterFolds[r]; ok {
+			b.WriteString(folded)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
+
+// Equal reports whether two strings are the same once folded, so "Beyoncé" equals "BEYONCE" and "ＡＢＣ" equals
+// "abc".
+func Equal(a string, b string) bool {
+	return Fold(a) == Fold(b)
+}
+
+// Clean removes emoji, symbols and punctuation other than ":" and "-" from a title while keeping letters and
+// digits of every script, and collapses the whitespace left behind. The text is only NFC normalized, so what is
+// written to tags keeps its original script and width.
+func Clean(str string) string {
+	str = unsafeChars.ReplaceAllString(norm.NFC.String(str), "")
+	return strings.TrimSpace(spaces.ReplaceAllString(str, " "))
+}
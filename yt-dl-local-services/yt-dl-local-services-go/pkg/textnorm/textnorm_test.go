@@ -0,0 +1,81 @@
+package textnorm
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Beyoncé", "beyonce"},
+		{"Sigur Rós", "sigur ros"},
+		{"ＡＢＣ１２３", "abc123"},
+		{"ﬁne", "fine"},
+		{"Straße", "strasse"},
+		{"Øresund", "oresund"},
+		{"Æther", "aether"},
+		{"İstanbul", "istanbul"},
+		{"Ёлка", "елка"},
+		{"Ελλάδα", "ελλαδα"},
+		{"ガギグ", "ガギグ"},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Beyoncé", "BEYONCE", true},
+		{"ＹＯＡＳＯＢＩ", "yoasobi", true},
+		{"Sigur Rós", "Sigur Ros", true},
+		{"Sigur Rós", "Sigur", false},
+	}
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.want {
+			t.Errorf("Equal(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Song 🔥🔥 (Official Video)!!", "Song Official Video"},
+		{"  A   B  ", "A B"},
+		{"Ｔｉｔｌｅ: Part-2", "Ｔｉｔｌｅ: Part-2"},
+	}
+	for _, tt := range tests {
+		if got := Clean(tt.in); got != tt.want {
+			t.Errorf("Clean(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Lemon", "lemon"},
+		{"Группа крови", "gruppa krovi"},
+		{"Ελλάδα", "ellada"},
+		{"よるにかける", "yorunikakeru"},
+		{"ヨルシカ", "yorushika"},
+		{"がっこう", "gakkou"},
+		{"きゃりーぱみゅぱみゅ", "kyariipamyupamyu"},
+		{"방탄소년단", "bangtansonyeondan"},
+	}
+	for _, tt := range tests {
+		if got := Transliterate(tt.in); got != tt.want {
+			t.Errorf("Transliterate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package textnorm
+
+import (
+	"strings"
+)
+
+// Transliterate spells Cyrillic, Greek, kana and Hangul in Latin letters so a title in its original script can be
+// compared with a romanized one. The romanization is deliberately simple, it only has to make both spellings
+// close, and kanji and hanzi are left as they are. It is for matching only, never for tags or file names.
+func Transliterate(str string) string {
+	var b strings.Builder
+	runes := []rune(Fold(str))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r >= 0xAC00 && r <= 0xD7A3:
+			b.WriteString(hangul(r))
+		case isKana(r):
+			i = kana(runes, i, &b)
+		default:
+			if latin, ok := cyrillic[r]; ok {
+				b.WriteString(latin)
+			} else if latin, ok := greek[r]; ok {
+				b.WriteString(latin)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ye", 'ж': "zh", 'з': "z",
+	'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'ђ': "dj", 'ј': "j", 'љ': "lj", 'њ': "nj",
+	'ћ': "c", 'џ': "dz",
+}
+
+var greek = map[rune]string{
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k",
+	'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t",
+	'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Revised Romanization of the initial, medial and final jamo of a Hangul syllable, without the sound changes
+// between syllables.
+var (
+	hangulInitials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulMedials  = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	hangulFinals   = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+)
+
+func hangul(r rune) string {
+	index := int(r - 0xAC00)
+	return hangulInitials[index/588] + hangulMedials[index%588/28] + hangulFinals[index%28]
+}
+
+// hiragana holds the Hepburn spelling of each kana, katakana is looked up by its hiragana counterpart.
+var hiragana = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko", 'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so", 'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to", 'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho", 'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o", 'ゎ': "wa",
+}
+
+// smallY are the small ya, yu and yo that merge with the kana before them, "き" "ゃ" is "kya".
+var smallY = map[rune]string{'ゃ': "a", 'ゅ': "u", 'ょ': "o"}
+
+func isKana(r rune) bool {
+	return r >= 0x3041 && r <= 0x3096 || r >= 0x30A1 && r <= 0x30FC
+}
+
+// toHiragana maps katakana to hiragana, the two blocks are laid out in the same order.
+func toHiragana(r rune) rune {
+	if r >= 0x30A1 && r <= 0x30F6 {
+		return r - 0x60
+	}
+	return r
+}
+
+// kana romanizes the kana at runes[i] and returns the index of the last rune it consumed.
+func kana(runes []rune, i int, b *strings.Builder) int {
+	r := toHiragana(runes[i])
+	switch r {
+	case 'っ':
+		// a small tsu doubles the consonant that follows it
+		if i+1 < len(runes) && isKana(runes[i+1]) {
+			if next := hiragana[toHiragana(runes[i+1])]; next != "" && !strings.ContainsAny(next[:1], "aiueon") {
+				b.WriteByte(next[0])
+			}
+		}
+		return i
+	case 'ー':
+		// the long vowel mark repeats the vowel before it
+		if str := b.String(); str != "" && strings.ContainsAny(str[len(str)-1:], "aiueo") {
+			b.WriteByte(str[len(str)-1])
+		}
+		return i
+	}
+	latin, ok := hiragana[r]
+	if !ok {
+		if vowel, small := smallY[r]; small {
+			b.WriteString("y" + vowel)
+		} else {
+			b.WriteRune(runes[i])
+		}
+		return i
+	}
+	if i+1 < len(runes) && strings.HasSuffix(latin, "i") {
+		if vowel, small := smallY[toHiragana(runes[i+1])]; small {
+			stem := strings.TrimSuffix(latin, "i")
+			if stem == "sh" || stem == "ch" || stem == "j" {
+				b.WriteString(stem + vowel)
+			} else {
+				b.WriteString(stem + "y" + vowel)
+			}
+			return i + 1
+		}
+	}
+	b.WriteString(latin)
+	return i
+}
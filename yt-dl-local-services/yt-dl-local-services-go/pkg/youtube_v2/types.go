@@ -2,6 +2,8 @@ package youtube_v2
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/http_client"
@@ -11,7 +13,9 @@ import (
 type YoutubeClient interface {
 	Download(ctx context.Context, id string, useEmbedded bool) ([]byte, error)
 	GetPlaylistEntries(ctx context.Context, playlistID string) ([]string, error)
+	GetPlaylistInfo(ctx context.Context, playlistID string) (*PlaylistInfo, error)
 	GetVideoInfo(ctx context.Context, videoID string, useEmbedded bool) (string, string, error)
+	GetCaptions(ctx context.Context, videoID string) (string, error)
 }
 
 type Client struct {
@@ -35,3 +39,33 @@ func NewYoutubeClient(config *config.Config, httpClient *http_client.HTTPClient)
 		YTEmbeddedClient: embeddedClient,
 	}
 }
+
+// PlaylistInfo is a playlist's title and its entries in playlist order.
+type PlaylistInfo struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	Author       string          `json:"author,omitempty"`
+	ThumbnailURL string          `json:"thumbnail_url,omitempty"`
+	Entries      []PlaylistEntry `json:"entries"`
+}
+
+type PlaylistEntry struct {
+	ID       string        `json:"id"`
+	Title    string        `json:"title"`
+	Author   string        `json:"author,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// IsAlbum reports whether the playlist is a YouTube Music album, album playlists share the OLAK5uy_ prefix.
+func (p *PlaylistInfo) IsAlbum() bool {
+	return strings.HasPrefix(p.ID, "OLAK5uy_")
+}
+
+// EntryIDs returns the video IDs of the playlist in order.
+func (p *PlaylistInfo) EntryIDs() []string {
+	ids := make([]string, 0, len(p.Entries))
+	for _, entry := range p.Entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gcottom/go-zaplog"
 	"github.com/kkdai/youtube/v2"
@@ -64,21 +66,30 @@ func (s *Client) Download(ctx context.Context, id string, useEmbedded bool) ([]b
 }
 
 func (s *Client) GetPlaylistEntries(ctx context.Context, playlistID string) ([]string, error) {
+	info, err := s.GetPlaylistInfo(ctx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	return info.EntryIDs(), nil
+}
+
+// GetPlaylistInfo returns the playlist title and its entries in playlist order
+func (s *Client) GetPlaylistInfo(ctx context.Context, playlistID string) (*PlaylistInfo, error) {
 	zaplog.InfoC(ctx, "getting playlist entries", zap.String("playlistID", playlistID))
 	playlist, err := s.YTClient.GetPlaylist(playlistID)
 	if err != nil {
 		zaplog.ErrorC(ctx, "failed to get playlist entries", zap.String("playlistID", playlistID), zap.Error(err))
-		return s.GetPlaylistEntriesFromMusicAPI(ctx, playlistID)
+		return s.GetPlaylistInfoFromMusicAPI(ctx, playlistID)
 	}
-	entries := make([]string, 0)
+	info := &PlaylistInfo{ID: playlistID, Title: playlist.Title, Author: playlist.Author, Entries: make([]PlaylistEntry, 0)}
 	for _, entry := range playlist.Videos {
-		entries = append(entries, entry.ID)
+		info.Entries = append(info.Entries, PlaylistEntry{ID: entry.ID, Title: entry.Title, Author: entry.Author, Duration: entry.Duration})
 	}
-	zaplog.InfoC(ctx, "successfully retrieved playlist entries", zap.String("playlistID", playlistID), zap.Int("count", len(entries)))
-	return entries, nil
+	zaplog.InfoC(ctx, "successfully retrieved playlist entries", zap.String("playlistID", playlistID), zap.Int("count", len(info.Entries)))
+	return info, nil
 }
 
-func (s *Client) GetPlaylistEntriesFromMusicAPI(ctx context.Context, playlistID string) ([]string, error) {
+func (s *Client) GetPlaylistInfoFromMusicAPI(ctx context.Context, playlistID string) (*PlaylistInfo, error) {
 	zaplog.InfoC(ctx, "getting playlist entries from music API", zap.String("playlistID", playlistID))
 	req, err := s.HTTPClient.CreateRequest(http.MethodGet, fmt.Sprintf("http://python_services_music_api:%d/playlist?id=%s", s.Config.LocalPortPython, playlistID), nil)
 	if err != nil {
@@ -95,20 +106,26 @@ func (s *Client) GetPlaylistEntriesFromMusicAPI(ctx context.Context, playlistID
 		return nil, fmt.Errorf("failed to get playlist entries from music API: %d", code)
 	}
 	var data struct {
-		Tracks []struct {
-			ID string `json:"id"`
+		Title     string `json:"title"`
+		Author    string `json:"author"`
+		Thumbnail string `json:"thumbnail"`
+		Tracks    []struct {
+			ID       string `json:"id"`
+			Title    string `json:"title"`
+			Author   string `json:"author"`
+			Duration int    `json:"duration"`
 		} `json:"tracks"`
 	}
 	if err := json.Unmarshal(resp, &data); err != nil {
 		zaplog.ErrorC(ctx, "failed to unmarshal response", zap.Error(err))
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-	entries := make([]string, 0)
+	info := &PlaylistInfo{ID: playlistID, Title: data.Title, Author: data.Author, ThumbnailURL: data.Thumbnail, Entries: make([]PlaylistEntry, 0)}
 	for _, entry := range data.Tracks {
-		entries = append(entries, entry.ID)
+		info.Entries = append(info.Entries, PlaylistEntry{ID: entry.ID, Title: entry.Title, Author: entry.Author, Duration: time.Duration(entry.Duration) * time.Second})
 	}
-	zaplog.InfoC(ctx, "successfully retrieved playlist entries from music API", zap.String("playlistID", playlistID), zap.Int("count", len(entries)))
-	return entries, nil
+	zaplog.InfoC(ctx, "successfully retrieved playlist entries from music API", zap.String("playlistID", playlistID), zap.Int("count", len(info.Entries)))
+	return info, nil
 }
 
 // GetVideoInfo returns the title and author of a video
@@ -133,6 +150,70 @@ func (s *Client) GetVideoInfo(ctx context.Context, videoID string, useEmbedded b
 	return video.Title, video.Author, nil
 }
 
+// GetCaptions returns the video's uploaded captions as LRC, auto-generated captions are skipped since they are
+// rarely usable as lyrics. An empty string means the video has no uploaded captions.
+func (s *Client) GetCaptions(ctx context.Context, videoID string) (string, error) {
+	zaplog.InfoC(ctx, "getting video captions", zap.String("videoID", videoID))
+	video, err := s.YTClient.GetVideoContext(ctx, videoID)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to get video info", zap.String("videoID", videoID), zap.Error(err))
+		return "", fmt.Errorf("failed to get video info: %w", err)
+	}
+	var track *youtube.CaptionTrack
+	for i, caption := range video.CaptionTracks {
+		if caption.Kind == "asr" {
+			continue
+		}
+		if track == nil || strings.HasPrefix(caption.LanguageCode, "en") && !strings.HasPrefix(track.LanguageCode, "en") {
+			track = &video.CaptionTracks[i]
+		}
+	}
+	if track == nil {
+		zaplog.InfoC(ctx, "video has no uploaded captions", zap.String("videoID", videoID))
+		return "", nil
+	}
+	req, err := s.HTTPClient.CreateRequest(http.MethodGet, track.BaseURL+"&fmt=json3", nil)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to create request", zap.Error(err))
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, code, err := s.HTTPClient.DoRequest(req)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to do request", zap.Error(err))
+		return "", fmt.Errorf("failed to do request: %w", err)
+	}
+	if code != http.StatusOK {
+		zaplog.ErrorC(ctx, "failed to get captions", zap.String("videoID", videoID), zap.Int("code", code))
+		return "", fmt.Errorf("failed to get captions: %d", code)
+	}
+	var data struct {
+		Events []struct {
+			StartMs int `json:"tStartMs"`
+			Segs    []struct {
+				Text string `json:"utf8"`
+			} `json:"segs"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		zaplog.ErrorC(ctx, "failed to unmarshal captions", zap.Error(err))
+		return "", fmt.Errorf("failed to unmarshal captions: %w", err)
+	}
+	var lrc strings.Builder
+	for _, event := range data.Events {
+		var line strings.Builder
+		for _, seg := range event.Segs {
+			line.WriteString(seg.Text)
+		}
+		text := strings.Join(strings.Fields(line.String()), " ")
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&lrc, "[%02d:%02d.%02d]%s\n", event.StartMs/60000, event.StartMs/1000%60, event.StartMs/10%100, text)
+	}
+	zaplog.InfoC(ctx, "successfully retrieved video captions", zap.String("videoID", videoID), zap.String("language", track.LanguageCode))
+	return lrc.String(), nil
+}
+
 func getBestAudioFormat(formats youtube.FormatList) *youtube.Format {
 	var bestFormat *youtube.Format
 	maxBitrate := 0
@@ -0,0 +1,72 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/youtube_v2"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+)
+
+// PlaylistContext tells a track where it sits in the playlist it was queued from, so its position survives
+// until tagging. Grouped is set for albums, and for ordinary playlists when playlist_as_album is enabled.
+type PlaylistContext struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	AlbumArtist string `json:"album_artist,omitempty"`
+	CoverArtURL string `json:"cover_art_url,omitempty"`
+	Index       int    `json:"index"`
+	Total       int    `json:"total"`
+	IsAlbum     bool   `json:"is_album"`
+	Grouped     bool   `json:"grouped"`
+}
+
+const variousArtists = "Various Artists"
+
+func (s *Service) newPlaylistContext(info *youtube_v2.PlaylistInfo, index int, opts DownloadOptions) *PlaylistContext {
+	asAlbum := s.Config.PlaylistAsAlbum
+	if opts.PlaylistAsAlbum != nil {
+		asAlbum = *opts.PlaylistAsAlbum
+	}
+	pl := &PlaylistContext{
+		ID:      info.ID,
+		Title:   info.Title,
+		Index:   index + 1,
+		Total:   len(info.Entries),
+		IsAlbum: info.IsAlbum(),
+		Grouped: info.IsAlbum() || asAlbum,
+	}
+	if pl.IsAlbum {
+		// album playlists are titled "Album - <name>" and owned by the artist's topic channel
+		pl.Title = strings.TrimPrefix(info.Title, "Album - ")
+		pl.AlbumArtist = strings.TrimSuffix(info.Author, " - Topic")
+	} else if pl.Grouped {
+		pl.AlbumArtist = variousArtists
+	}
+	pl.CoverArtURL = info.ThumbnailURL
+	if pl.CoverArtURL == "" && len(info.Entries) > 0 {
+		pl.CoverArtURL = fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", info.Entries[0].ID)
+	}
+	return pl
+}
+
+// applyPlaylistContext tags a grouped track with its position and a single album title, album artist and cover
+// shared by every track of the playlist. Ungrouped playlist tracks keep their own album and only get numbered
+// when they are grouped, so a normal playlist does not scatter track numbers across real albums.
+func applyPlaylistContext(trackMeta *meta.TrackMeta, pl *PlaylistContext) {
+	if pl == nil || !pl.Grouped {
+		return
+	}
+	trackMeta.Album = pl.Title
+	trackMeta.AlbumArtist = pl.AlbumArtist
+	if trackMeta.AlbumArtist == "" {
+		trackMeta.AlbumArtist = trackMeta.Artist
+	}
+	trackMeta.TrackNumber = pl.Index
+	trackMeta.TrackTotal = pl.Total
+	trackMeta.DiscNumber = 1
+	trackMeta.DiscTotal = 1
+	if pl.CoverArtURL != "" {
+		trackMeta.CoverArtURL = pl.CoverArtURL
+	}
+}
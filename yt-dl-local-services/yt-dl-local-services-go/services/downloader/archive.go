@@ -0,0 +1,41 @@
+package downloader
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/filestore"
+)
+
+// Archive is the persistent record of every video ID that has been downloaded and saved successfully.
+type Archive struct {
+	mu   sync.RWMutex
+	path string
+	IDs  map[string]time.Time `json:"ids"`
+}
+
+func NewArchive(stateDir string) (*Archive, error) {
+	a := &Archive{path: filepath.Join(stateDir, "archive.json"), IDs: make(map[string]time.Time)}
+	if err := filestore.Load(a.path, a); err != nil {
+		return nil, err
+	}
+	if a.IDs == nil {
+		a.IDs = make(map[string]time.Time)
+	}
+	return a, nil
+}
+
+func (a *Archive) Has(id string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	_, ok := a.IDs[id]
+	return ok
+}
+
+func (a *Archive) Add(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.IDs[id] = time.Now()
+	return filestore.Save(a.path, a)
+}
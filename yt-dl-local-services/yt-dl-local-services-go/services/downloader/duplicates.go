@@ -0,0 +1,33 @@
+package downloader
+
+import (
+	"context"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/library"
+	"go.uber.org/zap"
+)
+
+// What happens to a finished track that sounds the same as a file already in the library.
+const (
+	DuplicateOff  = "off"
+	DuplicateFlag = "flag"
+	DuplicateSkip = "skip"
+)
+
+// FindDuplicate returns the library file a finished track sounds the same as. Tracks without a fingerprint, or
+// with duplicate detection off, have none. A failed lookup is logged and the track is saved as usual.
+func (s *Service) FindDuplicate(ctx context.Context, id string, fingerprint string) *library.Entry {
+	if fingerprint == "" || s.Config.DuplicateAction == DuplicateOff {
+		return nil
+	}
+	duplicate, similarity, err := s.LibraryService.FindDuplicate(fingerprint, id)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to look up duplicates", zap.String("id", id), zap.Error(err))
+		return nil
+	}
+	if duplicate != nil {
+		zaplog.InfoC(ctx, "track sounds the same as a library file", zap.String("id", id), zap.String("path", duplicate.Path), zap.Float64("similarity", similarity))
+	}
+	return duplicate
+}
@@ -0,0 +1,113 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/pathtemplate"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"go.uber.org/zap"
+)
+
+// SavePath renders the filename template for a finished track. The request's template wins over the configured
+// one. If the rendered path is already taken by a file from a different video, or is being saved for one, the
+// video ID is appended to the name so both tracks are kept and the same track always lands on the same path.
+// The path stays reserved for the track until release is called, which must happen once the file is saved and
+// indexed.
+func (s *Service) SavePath(ctx context.Context, trackMeta *meta.TrackMeta, opts DownloadOptions, remoteName string) (path string, release func()) {
+	template := opts.Template
+	if template == "" {
+		template = s.Config.FilenameTemplate
+	}
+	rel, err := pathtemplate.Render(template, s.templateValues(trackMeta, remoteName))
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to render filename template, using the configured template", zap.String("template", template), zap.Error(err))
+		rel, _ = pathtemplate.Render(s.Config.FilenameTemplate, s.templateValues(trackMeta, remoteName))
+	}
+	path = filepath.Join(s.Config.SaveDir, filepath.FromSlash(rel))
+
+	// the check and the reservation are one step so two tracks saved at once cannot both take a free path
+	s.savingMu.Lock()
+	defer s.savingMu.Unlock()
+	if s.pathTakenByOtherTrack(path, trackMeta.ID) {
+		suffixed := filepath.Join(s.Config.SaveDir, filepath.FromSlash(pathtemplate.WithSuffix(rel, fmt.Sprintf(" [%s]", trackMeta.ID))))
+		zaplog.InfoC(ctx, "filename collision with a different track", zap.String("path", path), zap.String("resolved", suffixed))
+		path = suffixed
+	}
+	s.saving[path] = trackMeta.ID
+	return path, func() {
+		s.savingMu.Lock()
+		defer s.savingMu.Unlock()
+		if s.saving[path] == trackMeta.ID {
+			delete(s.saving, path)
+		}
+	}
+}
+
+func (s *Service) templateValues(trackMeta *meta.TrackMeta, remoteName string) map[string]string {
+	ext := strings.TrimPrefix(filepath.Ext(remoteName), ".")
+	if ext == "" {
+		ext = "mp3"
+	}
+	artist := trackMeta.Artist
+	if artist == "" {
+		artist = "Unknown Artist"
+	}
+	albumArtist := trackMeta.AlbumArtist
+	if albumArtist == "" {
+		albumArtist = artist
+	}
+	album := trackMeta.Album
+	if album == "" {
+		album = "Unknown Album"
+	}
+	return map[string]string{
+		"id":           trackMeta.ID,
+		"title":        trackMeta.Title,
+		"artist":       artist,
+		"album_artist": albumArtist,
+		"album":        album,
+		"genre":        trackMeta.Genre,
+		"year":         positive(trackMeta.Year),
+		"track":        positive(trackMeta.TrackNumber),
+		"track_total":  positive(trackMeta.TrackTotal),
+		"disc":         positive(trackMeta.DiscNumber),
+		"ext":          ext,
+	}
+}
+
+func positive(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// pathTakenByOtherTrack reports whether path is being saved for another video, or a file exists at path that the
+// library does not attribute to the video id. Files the library knows nothing about are treated as belonging to
+// another track so they are never overwritten. The caller must hold s.savingMu.
+func (s *Service) pathTakenByOtherTrack(path string, id string) bool {
+	if saving, ok := s.saving[path]; ok {
+		return saving != id
+	}
+	if _, err := os.Stat(path); err != nil {
+		return false
+	}
+	entry, ok := s.LibraryService.GetEntry(path)
+	return !ok || entry.YoutubeID != id
+}
+
+// WriteLyricsSidecar writes the track's synced lyrics to an .lrc file next to the saved audio file, for players
+// that read sidecar lyrics instead of the embedded SYLT frame.
+func (s *Service) WriteLyricsSidecar(audioPath string, trackMeta *meta.TrackMeta) error {
+	if !s.Config.LyricsSidecar || trackMeta.SyncedLyrics == "" {
+		return nil
+	}
+	lrcPath := strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".lrc"
+	return os.WriteFile(lrcPath, []byte(trackMeta.SyncedLyrics), 0644)
+}
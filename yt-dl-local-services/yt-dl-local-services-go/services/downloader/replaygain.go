@@ -0,0 +1,109 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/audiotags"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/youtube_v2"
+	"go.uber.org/zap"
+)
+
+// replayGainReference is the loudness ReplayGain 2.0 gains are relative to, in LUFS.
+const replayGainReference = -18.0
+
+// albumGainTrack is a saved track of an album with the loudness the Lambda measured for it.
+type albumGainTrack struct {
+	id       string
+	path     string
+	loudness float64
+	peak     float64
+	weight   float64
+}
+
+// WriteAlbumGain tags the saved tracks of a grouped playlist with the album's ReplayGain once every entry has
+// finished. Only files still tagged with the playlist's album take part, an entry saved earlier as part of another
+// album keeps that album's gain. The album is skipped when one of its tracks has no track gain, the album gain
+// would not account for it.
+func (s *Service) WriteAlbumGain(ctx context.Context, info *youtube_v2.PlaylistInfo, album string) {
+	tracks := make([]albumGainTrack, 0, len(info.Entries))
+	seen := make(map[string]bool, len(info.Entries))
+	for _, entry := range info.Entries {
+		if seen[entry.ID] {
+			continue
+		}
+		seen[entry.ID] = true
+		libraryEntry, ok := s.LibraryService.FindByYoutubeID(entry.ID)
+		if !ok || libraryEntry.Album != album {
+			continue
+		}
+		path := filepath.Join(s.Config.SaveDir, filepath.FromSlash(libraryEntry.Path))
+		track, err := readAlbumGainTrack(path)
+		if err != nil {
+			zaplog.ErrorC(ctx, "skipping album gain", zap.String("id", info.ID), zap.String("path", path), zap.Error(err))
+			return
+		}
+		track.id = entry.ID
+		tracks = append(tracks, *track)
+	}
+	if len(tracks) == 0 {
+		return
+	}
+	gain, peak := albumGain(tracks)
+	fields := audiotags.Fields{
+		audiotags.FieldReplayGainAlbumGain: fmt.Sprintf("%.2f dB", gain),
+		audiotags.FieldReplayGainAlbumPeak: fmt.Sprintf("%.6f", peak),
+	}
+	zaplog.InfoC(ctx, "writing album gain", zap.String("id", info.ID), zap.Int("tracks", len(tracks)), zap.Float64("gain", gain), zap.Float64("peak", peak))
+	for _, track := range tracks {
+		if err := audiotags.WriteFields(track.path, fields, nil); err != nil {
+			zaplog.ErrorC(ctx, "failed to write album gain", zap.String("path", track.path), zap.Error(err))
+			continue
+		}
+		if err := s.LibraryService.AddFile(ctx, track.path, track.id); err != nil {
+			zaplog.ErrorC(ctx, "failed to update library entry", zap.String("path", track.path), zap.Error(err))
+		}
+	}
+}
+
+// readAlbumGainTrack reads a track's gain and peak back from its tags and weighs it by its duration.
+func readAlbumGainTrack(path string) (*albumGainTrack, error) {
+	fields, err := audiotags.ReadFields(path)
+	if err != nil {
+		return nil, err
+	}
+	if fields[audiotags.FieldReplayGainTrackGain] == "" {
+		return nil, fmt.Errorf("no track gain")
+	}
+	gain, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(fields[audiotags.FieldReplayGainTrackGain], "dB")), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid track gain: %w", err)
+	}
+	peak, err := strconv.ParseFloat(strings.TrimSpace(fields[audiotags.FieldReplayGainTrackPeak]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid track peak: %w", err)
+	}
+	track := &albumGainTrack{path: path, loudness: replayGainReference - gain, peak: peak, weight: 1}
+	if format, err := audiotags.ReadFormat(path); err == nil && format.DurationMs > 0 {
+		track.weight = float64(format.DurationMs)
+	}
+	return track, nil
+}
+
+// albumGain returns the ReplayGain of an album: its loudness is the energy average of the track loudness weighted
+// by duration, the same as measuring the tracks played back to back apart from the gating, and its peak is the
+// highest track peak.
+func albumGain(tracks []albumGainTrack) (float64, float64) {
+	var energy, weight, peak float64
+	for _, track := range tracks {
+		energy += track.weight * math.Pow(10, track.loudness/10)
+		weight += track.weight
+		peak = max(peak, track.peak)
+	}
+	return replayGainReference - 10*math.Log10(energy/weight), peak
+}
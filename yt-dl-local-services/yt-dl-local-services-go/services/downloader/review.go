@@ -0,0 +1,153 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNoReview      = errors.New("download is not waiting for review")
+	ErrInvalidReview = errors.New("invalid review")
+)
+
+const (
+	ReviewActionYoutube = "youtube"
+	ReviewActionBest    = "best"
+	ReviewActionFail    = "fail"
+
+	maxReviewCandidates = 5
+)
+
+// Review is the metadata choice a download in needs_review is waiting for. It is shown in the download's status.
+type Review struct {
+	YoutubeTitle  string            `json:"youtube_title"`
+	YoutubeAuthor string            `json:"youtube_author"`
+	Candidates    []meta.ScoredMeta `json:"candidates"`
+	Reason        string            `json:"reason,omitempty"`
+	Deadline      time.Time         `json:"deadline"`
+	DefaultAction string            `json:"default_action"`
+
+	fallback *meta.TrackMeta
+	decision chan *meta.TrackMeta
+}
+
+// ReviewDecision picks one of the review's candidates by index, or gives the title, artist and album by hand.
+// Fields given next to a candidate override the candidate's values, a decision by hand keeps the album of the
+// fallback metadata unless Album is given.
+type ReviewDecision struct {
+	Candidate *int   `json:"candidate,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Artist    string `json:"artist,omitempty"`
+	Album     string `json:"album,omitempty"`
+}
+
+// ReviewMeta returns the metadata to tag a download with. Confident matches pass straight through, unsure ones
+// put the download in needs_review until SubmitReview is called or the review times out to the default action.
+func (s *Service) ReviewMeta(ctx context.Context, id string, result *meta.MetaResult) (*meta.TrackMeta, error) {
+	if result.Matched || s.Config.DisableMetadataReview || len(result.Candidates) == 0 || result.Candidates[0].Score < s.Config.MetadataReviewMinScore {
+		return result.Meta, nil
+	}
+	timeout := time.Duration(s.Config.MetadataReviewTimeoutMinutes) * time.Minute
+	review := &Review{
+		YoutubeTitle:  result.YoutubeMeta.Title,
+		YoutubeAuthor: result.YoutubeMeta.Artist,
+		Candidates:    result.Candidates[:min(len(result.Candidates), maxReviewCandidates)],
+		Reason:        result.Meta.MatchNote,
+		Deadline:      time.Now().Add(timeout),
+		DefaultAction: s.Config.MetadataReviewDefaultAction,
+		fallback:      result.Meta,
+		decision:      make(chan *meta.TrackMeta, 1),
+	}
+	s.reviewMu.Lock()
+	s.Reviews[id] = review
+	s.reviewMu.Unlock()
+	zaplog.InfoC(ctx, "metadata needs review", zap.String("id", id), zap.String("reason", review.Reason), zap.Float64("bestScore", review.Candidates[0].Score))
+	s.StatusQueue <- StatusUpdate{ID: id, Status: StatusNeedsReview, TrackArtist: result.Meta.Artist, TrackTitle: result.Meta.Title, Review: review}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var trackMeta *meta.TrackMeta
+	select {
+	case trackMeta = <-review.decision:
+		zaplog.InfoC(ctx, "metadata review submitted", zap.String("id", id), zap.String("title", trackMeta.Title), zap.String("artist", trackMeta.Artist))
+		if s.MetaServiceClient.Cache != nil {
+			// the choice sticks for later re-downloads of the same video
+			if err := s.MetaServiceClient.Cache.SetReviewed(id, *trackMeta); err != nil {
+				zaplog.ErrorC(ctx, "failed to cache reviewed meta", zap.String("id", id), zap.Error(err))
+			}
+		}
+	case <-timer.C:
+		s.reviewMu.Lock()
+		delete(s.Reviews, id)
+		s.reviewMu.Unlock()
+		zaplog.InfoC(ctx, "metadata review timed out", zap.String("id", id), zap.String("defaultAction", review.DefaultAction))
+		// a decision may have been submitted just before the review was removed
+		select {
+		case trackMeta = <-review.decision:
+		default:
+			switch review.DefaultAction {
+			case ReviewActionFail:
+				return nil, fmt.Errorf("metadata review timed out")
+			case ReviewActionBest:
+				best := review.Candidates[0].Meta
+				trackMeta = &best
+			default:
+				trackMeta = review.fallback
+			}
+		}
+	}
+	if trackMeta.CoverArtURL == "" || trackMeta.CoverArtURL != review.fallback.CoverArtURL && !s.MetaServiceClient.CoverArtExists(ctx, trackMeta.CoverArtURL) {
+		trackMeta.CoverArtURL = review.fallback.CoverArtURL
+	}
+	s.StatusQueue <- StatusUpdate{ID: id, Status: StatusProcessing, TrackArtist: trackMeta.Artist, TrackTitle: trackMeta.Title}
+	return trackMeta, nil
+}
+
+// SubmitReview resolves a download waiting in needs_review, after which it continues to processing.
+func (s *Service) SubmitReview(ctx context.Context, id string, decision ReviewDecision) error {
+	s.reviewMu.Lock()
+	defer s.reviewMu.Unlock()
+	review, ok := s.Reviews[id]
+	if !ok {
+		return ErrNoReview
+	}
+	trackMeta, err := review.resolve(decision)
+	if err != nil {
+		return err
+	}
+	delete(s.Reviews, id)
+	review.decision <- trackMeta
+	return nil
+}
+
+func (r *Review) resolve(decision ReviewDecision) (*meta.TrackMeta, error) {
+	var trackMeta meta.TrackMeta
+	if decision.Candidate != nil {
+		if *decision.Candidate < 0 || *decision.Candidate >= len(r.Candidates) {
+			return nil, fmt.Errorf("%w: candidate %d out of range", ErrInvalidReview, *decision.Candidate)
+		}
+		trackMeta = r.Candidates[*decision.Candidate].Meta
+	} else {
+		if decision.Title == "" || decision.Artist == "" {
+			return nil, fmt.Errorf("%w: a candidate or a title and artist are required", ErrInvalidReview)
+		}
+		trackMeta = *r.fallback
+	}
+	if decision.Title != "" {
+		trackMeta.Title = decision.Title
+	}
+	if decision.Artist != "" {
+		trackMeta.Artist = decision.Artist
+	}
+	if decision.Album != "" {
+		trackMeta.Album = decision.Album
+	}
+	trackMeta.MatchNote = "reviewed"
+	return &trackMeta, nil
+}
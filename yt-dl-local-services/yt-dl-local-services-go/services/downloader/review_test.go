@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+)
+
+func TestReviewMeta(t *testing.T) {
+	candidate := func(i int) *int { return &i }
+	tests := []struct {
+		name     string
+		decision ReviewDecision
+		want     meta.TrackMeta
+	}{
+		{"candidate", ReviewDecision{Candidate: candidate(1)},
+			meta.TrackMeta{Title: "Song (Live)", Artist: "Artist", Album: "Live Album", CoverArtURL: "fallback.jpg", MatchNote: "reviewed"}},
+		{"candidate with override", ReviewDecision{Candidate: candidate(0), Album: "Other Album"},
+			meta.TrackMeta{Title: "Song", Artist: "Artist", Album: "Other Album", CoverArtURL: "fallback.jpg", MatchNote: "reviewed"}},
+		{"by hand", ReviewDecision{Title: "Real Song", Artist: "Real Artist"},
+			meta.TrackMeta{Title: "Real Song", Artist: "Real Artist", Album: "YouTube Album", CoverArtURL: "fallback.jpg", MatchNote: "reviewed"}},
+		{"by hand with album", ReviewDecision{Title: "Real Song", Artist: "Real Artist", Album: "Real Album"},
+			meta.TrackMeta{Title: "Real Song", Artist: "Real Artist", Album: "Real Album", CoverArtURL: "fallback.jpg", MatchNote: "reviewed"}},
+	}
+	for _, tt := range tests {
+		s := &Service{
+			Config:            &config.Config{MetadataReviewMinScore: 0.5, MetadataReviewTimeoutMinutes: 1, MetadataReviewDefaultAction: ReviewActionYoutube},
+			StatusQueue:       make(chan StatusUpdate, 10),
+			MetaServiceClient: &meta.Service{},
+			Reviews:           make(map[string]*Review),
+		}
+		result := &meta.MetaResult{
+			Meta:        &meta.TrackMeta{Title: "Song", Artist: "Artist", Album: "YouTube Album", CoverArtURL: "fallback.jpg", MatchNote: "no confident match"},
+			YoutubeMeta: meta.TrackMeta{Title: "Artist - Song", Artist: "Artist"},
+			Candidates: []meta.ScoredMeta{
+				{Meta: meta.TrackMeta{Title: "Song", Artist: "Artist", Album: "Album"}, Score: 0.7},
+				{Meta: meta.TrackMeta{Title: "Song (Live)", Artist: "Artist", Album: "Live Album"}, Score: 0.6},
+			},
+		}
+		type reviewed struct {
+			trackMeta *meta.TrackMeta
+			err       error
+		}
+		done := make(chan reviewed, 1)
+		go func() {
+			trackMeta, err := s.ReviewMeta(context.Background(), "id", result)
+			done <- reviewed{trackMeta, err}
+		}()
+
+		update := <-s.StatusQueue
+		if update.Status != StatusNeedsReview || update.Review == nil || len(update.Review.Candidates) != 2 {
+			t.Fatalf("%s: first status update %+v, want needs_review with 2 candidates", tt.name, update)
+		}
+		if err := s.SubmitReview(context.Background(), "id", ReviewDecision{Candidate: candidate(2)}); !errors.Is(err, ErrInvalidReview) {
+			t.Errorf("%s: out of range candidate returned %v, want ErrInvalidReview", tt.name, err)
+		}
+		if err := s.SubmitReview(context.Background(), "id", tt.decision); err != nil {
+			t.Fatalf("%s: SubmitReview() returned %v", tt.name, err)
+		}
+		if err := s.SubmitReview(context.Background(), "id", tt.decision); !errors.Is(err, ErrNoReview) {
+			t.Errorf("%s: second SubmitReview() returned %v, want ErrNoReview", tt.name, err)
+		}
+
+		select {
+		case got := <-done:
+			if got.err != nil {
+				t.Fatalf("%s: ReviewMeta() returned %v", tt.name, got.err)
+			}
+			if !reflect.DeepEqual(*got.trackMeta, tt.want) {
+				t.Errorf("%s: ReviewMeta() = %+v, want %+v", tt.name, *got.trackMeta, tt.want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: ReviewMeta() did not return after the review was submitted", tt.name)
+		}
+		if update := <-s.StatusQueue; update.Status != StatusProcessing {
+			t.Errorf("%s: second status update %q, want %q", tt.name, update.Status, StatusProcessing)
+		}
+	}
+}
+
+func TestReviewMetaConfidentMatch(t *testing.T) {
+	s := &Service{Config: &config.Config{MetadataReviewMinScore: 0.5}, Reviews: make(map[string]*Review)}
+	want := &meta.TrackMeta{Title: "Song", Artist: "Artist"}
+	got, err := s.ReviewMeta(context.Background(), "id", &meta.MetaResult{Meta: want, Matched: true})
+	if err != nil || got != want {
+		t.Errorf("ReviewMeta() = %v, %v, want the matched meta", got, err)
+	}
+}
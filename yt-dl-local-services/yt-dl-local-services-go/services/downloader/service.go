@@ -7,52 +7,77 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/gcottom/go-zaplog"
 	"github.com/gcottom/retry"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/pathtemplate"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/youtube_v2"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
 	"go.uber.org/zap"
 )
 
-func (s *Service) InitiateDownload(ctx context.Context, id string) error {
+func (s *Service) InitiateDownload(ctx context.Context, id string, opts DownloadOptions) error {
+	if opts.Template != "" {
+		if err := pathtemplate.Validate(opts.Template); err != nil {
+			return fmt.Errorf("invalid filename template: %w", err)
+		}
+	}
+	if !s.IsTrack(id) && opts.Overrides != nil && (opts.Overrides.Title != "" || opts.Overrides.Artist != "") {
+		return fmt.Errorf("title and artist overrides are only allowed for single tracks")
+	}
 	s.StatusQueue <- StatusUpdate{ID: id, Status: StatusQueued}
-	s.DownloadQueue <- id
+	s.DownloadQueue <- DownloadRequest{ID: id, Options: opts}
 	return nil
 }
 
 func (s *Service) DLQueueProcessor() {
 	for {
 		select {
-		case id := <-s.DownloadQueue:
+		case req := <-s.DownloadQueue:
+			id := req.ID
 			if s.IsTrack(id) {
 				s.DownloadLimiter.Acquire()
 				go func(id string) {
-					defer s.DownloadLimiter.Release()
+					// the download slot is freed once the file is uploaded, a download waiting for review must not hold it
+					release := sync.OnceFunc(s.DownloadLimiter.Release)
+					defer release()
 					s.StatusQueue <- StatusUpdate{ID: id, Status: StatusDownloading}
 					if _, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s.RunDownload, context.Background(), id); err != nil {
 						s.StatusQueue <- StatusUpdate{ID: id, Status: StatusFailed}
 						return
 					}
-					metaIn, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s.ProcessDownload, context.Background(), id)
+					resultIn, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s.ProcessDownload, context.Background(), id, req.Options.Overrides)
+					release()
 					if err != nil {
 						s.StatusQueue <- StatusUpdate{ID: id, Status: StatusFailed}
 						return
 					}
-					if len(metaIn) == 0 {
+					if len(resultIn) == 0 {
 						s.StatusQueue <- StatusUpdate{ID: id, Status: StatusFailed}
 						return
 					}
-					meta, ok := metaIn[0].(*meta.TrackMeta)
+					result, ok := resultIn[0].(*meta.MetaResult)
 					if !ok {
 						s.StatusQueue <- StatusUpdate{ID: id, Status: StatusFailed}
 						return
 					}
-					go s.ScheduledProcessingCallback(context.Background(), meta)
+					trackMeta, err := s.ReviewMeta(context.Background(), id, result)
+					if err != nil {
+						s.StatusQueue <- StatusUpdate{ID: id, Status: StatusFailed, Warning: err.Error()}
+						return
+					}
+					s.PrepareMeta(context.Background(), id, trackMeta, req.Options)
+					if _, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s.InitiateProcessing, context.Background(), trackMeta); err != nil {
+						s.StatusQueue <- StatusUpdate{ID: id, Status: StatusFailed}
+						return
+					}
+					go s.ScheduledProcessingCallback(context.Background(), trackMeta, result.YoutubeMeta, req.Options)
 				}(id)
 			} else {
-				go s.PlaylistProcessingCallback(context.Background(), id)
+				go s.PlaylistProcessingCallback(context.Background(), id, req.Options)
 			}
 		default:
 			time.Sleep(1 * time.Second)
@@ -86,7 +111,7 @@ func (s *Service) RunDownload(ctx context.Context, id string) error {
 	return exec.Command("./downloader", fmt.Sprintf("-id=%s", id)).Run()
 }
 
-func (s *Service) ProcessDownload(ctx context.Context, id string) (*meta.TrackMeta, error) {
+func (s *Service) ProcessDownload(ctx context.Context, id string, overrides *meta.Overrides) (*meta.MetaResult, error) {
 	path := fmt.Sprintf("%s/%s", s.Config.TempDir, id)
 	file, err := os.Open(path)
 	if err != nil {
@@ -128,34 +153,57 @@ func (s *Service) ProcessDownload(ctx context.Context, id string) (*meta.TrackMe
 	if code != http.StatusOK {
 		return nil, fmt.Errorf("failed to upload file: %d", code)
 	}
-	trackMeta, err := s.MetaServiceClient.GetBestMeta(ctx, id)
+	result, err := s.MetaServiceClient.GetBestMeta(ctx, id, overrides)
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
+
+// PrepareMeta completes the chosen metadata before processing with the featured artists in the configured place,
+// the track's playlist position, the request's overrides and its lyrics.
+func (s *Service) PrepareMeta(ctx context.Context, id string, trackMeta *meta.TrackMeta, opts DownloadOptions) {
 	trackMeta.ID = id
+	s.MetaServiceClient.ApplyArtistStyle(trackMeta)
+	applyPlaylistContext(trackMeta, opts.Playlist)
+	opts.Overrides.Apply(trackMeta)
+	trackLyrics, err := s.LyricsService.GetLyrics(ctx, trackMeta)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to get lyrics", zap.String("id", id), zap.Error(err))
+	} else if trackLyrics != nil {
+		zaplog.InfoC(ctx, "found lyrics", zap.String("id", id), zap.String("source", trackLyrics.Source), zap.Bool("synced", trackLyrics.Synced != ""))
+		trackMeta.Lyrics = trackLyrics.Plain
+		trackMeta.SyncedLyrics = trackLyrics.Synced
+	}
+}
+
+// InitiateProcessing hands the uploaded file and its metadata to the Lambda pipeline.
+func (s *Service) InitiateProcessing(ctx context.Context, trackMeta *meta.TrackMeta) error {
 	jsonData, err := json.Marshal(trackMeta)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	req, err = s.HTTPClient.CreateRequest(http.MethodPost, fmt.Sprintf("https://%s/initiator", s.Config.LambdaDomain), jsonData)
+	req, err := s.HTTPClient.CreateRequest(http.MethodPost, fmt.Sprintf("https://%s/initiator", s.Config.LambdaDomain), jsonData)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	res, code, err := s.HTTPClient.DoRequest(req)
 	if err != nil {
 		zaplog.Error("failed to initiate processing", zap.Error(err))
-		return nil, fmt.Errorf("failed to initiate processing: %w", err)
+		return fmt.Errorf("failed to initiate processing: %w", err)
 	}
 	if code != http.StatusOK {
 		zaplog.Error("failed to initiate processing", zap.Int("code", code), zap.String("response", string(res)))
-		return nil, fmt.Errorf("failed to initiate processing: %d", code)
+		return fmt.Errorf("failed to initiate processing: %d", code)
 	}
-	return trackMeta, nil
+	return nil
 }
 
-func (s *Service) ScheduledProcessingCallback(ctx context.Context, meta *meta.TrackMeta) {
+func (s *Service) ScheduledProcessingCallback(ctx context.Context, meta *meta.TrackMeta, youtubeMeta meta.TrackMeta, opts DownloadOptions) {
 	start := time.Now()
 	id := meta.ID
+	// warning is reported with the final status, a flagged duplicate is saved but the caller is told
+	warning := ""
 	for {
 		s.StatusQueue <- StatusUpdate{ID: id, Status: StatusProcessing}
 		if time.Since(start) > 3600*time.Second {
@@ -172,17 +220,49 @@ func (s *Service) ScheduledProcessingCallback(ctx context.Context, meta *meta.Tr
 		}
 		if status[0] != nil && status[0].(*ProcessingStatus).Status == StatusComplete {
 			zaplog.InfoC(ctx, "processing callback running - got processing status", zap.String("id", id), zap.String("status", status[0].(*ProcessingStatus).Status))
+			fingerprint := status[0].(*ProcessingStatus).Fingerprint
+			duplicate := s.FindDuplicate(ctx, id, fingerprint)
+			if duplicate != nil && s.Config.DuplicateAction == DuplicateSkip {
+				zaplog.InfoC(ctx, "skipping duplicate track", zap.String("id", id), zap.String("duplicateOf", duplicate.Path))
+				if err := s.Archive.Add(id); err != nil {
+					zaplog.ErrorC(ctx, "failed to record download in archive", zap.String("id", id), zap.Error(err))
+				}
+				s.StatusQueue <- StatusUpdate{ID: id, TrackArtist: meta.Artist, TrackTitle: meta.Title, Status: StatusComplete, Warning: fmt.Sprintf("not saved, sounds the same as %s", duplicate.Path)}
+				return
+			}
 			s.SaveFileLimiter.Acquire()
 			defer s.SaveFileLimiter.Release()
-			if _, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s.SaveProcessedFile, ctx, status[0].(*ProcessingStatus).FileName, status[0].(*ProcessingStatus).FileURL); err != nil {
+			savePath, release := s.SavePath(ctx, meta, opts, status[0].(*ProcessingStatus).FileName)
+			defer release()
+			saved, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s.SaveProcessedFile, ctx, savePath, status[0].(*ProcessingStatus).FileURL)
+			if err != nil {
 				zaplog.ErrorC(ctx, "failed to save processed file", zap.String("id", id), zap.Error(err))
 				s.StatusQueue <- StatusUpdate{ID: id, TrackArtist: meta.Artist, TrackTitle: meta.Title, Status: StatusFailed}
 				return
 			}
+			if err := s.Archive.Add(id); err != nil {
+				zaplog.ErrorC(ctx, "failed to record download in archive", zap.String("id", id), zap.Error(err))
+			}
+			if err := s.LibraryService.AddFile(ctx, saved[0].(string), id); err != nil {
+				zaplog.ErrorC(ctx, "failed to add saved file to library", zap.String("id", id), zap.Error(err))
+			} else if fingerprint != "" {
+				duplicateOf := ""
+				if duplicate != nil {
+					duplicateOf = duplicate.Path
+					warning = fmt.Sprintf("sounds the same as %s", duplicate.Path)
+				}
+				if err := s.LibraryService.SetFingerprint(ctx, saved[0].(string), fingerprint, duplicateOf); err != nil {
+					zaplog.ErrorC(ctx, "failed to save fingerprint", zap.String("id", id), zap.Error(err))
+				}
+			}
+			if err := s.WriteLyricsSidecar(saved[0].(string), meta); err != nil {
+				zaplog.ErrorC(ctx, "failed to write lyrics sidecar", zap.String("id", id), zap.Error(err))
+			}
+			s.WriteSidecars(ctx, saved[0].(string), meta, youtubeMeta, start)
 		}
 		if status[0] != nil {
 			zaplog.InfoC(ctx, "processing callback running - got processing status", zap.String("id", id), zap.String("status", status[0].(*ProcessingStatus).Status))
-			s.StatusQueue <- StatusUpdate{ID: id, TrackArtist: meta.Artist, TrackTitle: meta.Title, Status: status[0].(*ProcessingStatus).Status}
+			s.StatusQueue <- StatusUpdate{ID: id, TrackArtist: meta.Artist, TrackTitle: meta.Title, Status: status[0].(*ProcessingStatus).Status, Warning: warning}
 		}
 		if status[0] != nil && status[0].(*ProcessingStatus).Status == StatusComplete || status[0].(*ProcessingStatus).Status == StatusFailed {
 			zaplog.InfoC(ctx, "processing callback exiting", zap.String("id", id), zap.String("status", status[0].(*ProcessingStatus).Status))
@@ -192,9 +272,13 @@ func (s *Service) ScheduledProcessingCallback(ctx context.Context, meta *meta.Tr
 	}
 }
 
-func (s *Service) PlaylistProcessingCallback(ctx context.Context, id string) {
+func (s *Service) PlaylistProcessingCallback(ctx context.Context, id string, opts DownloadOptions) {
 	s.StatusQueue <- StatusUpdate{ID: id, Status: StatusQueued}
-	entries, err := s.YoutubeClient.GetPlaylistEntries(ctx, id)
+	var entries []string
+	info, err := s.YoutubeClient.GetPlaylistInfo(ctx, id)
+	if err == nil {
+		entries = info.EntryIDs()
+	}
 	if len(entries) > 10 {
 		s.StatusQueue <- StatusUpdate{ID: id, Status: StatusWarning, Warning: fmt.Sprintf("Playlist length is %d, downloading this many tracks may result in a ban. Are you sure you want to continue?", len(entries))}
 		timeStart := time.Now()
@@ -225,8 +309,65 @@ func (s *Service) PlaylistProcessingCallback(ctx context.Context, id string) {
 		zaplog.ErrorC(ctx, "failed to get playlist entries", zap.String("id", id), zap.Error(err))
 		return
 	}
+	if _, err := s.PlaylistService.SavePlaylist(ctx, info); err != nil {
+		zaplog.ErrorC(ctx, "failed to save playlist manifest", zap.String("id", id), zap.Error(err))
+	}
+	s.TrackPlaylistEntries(ctx, info, entries, opts)
+}
+
+// QueuePlaylistEntries downloads a subset of a playlist's entries without the large playlist warning,
+// reporting progress under the playlist ID. It is used for scheduled syncs where the caller has already
+// decided which entries are new. The playlist file is rewritten once the entries finish, or straight away
+// when nothing new was queued but the playlist itself changed.
+func (s *Service) QueuePlaylistEntries(ctx context.Context, info *youtube_v2.PlaylistInfo, entries []string, opts DownloadOptions) error {
+	changed, err := s.PlaylistService.SavePlaylist(ctx, info)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to save playlist manifest", zap.String("id", info.ID), zap.Error(err))
+	}
+	if len(entries) == 0 {
+		if changed {
+			s.writePlaylistFile(ctx, info.ID)
+		}
+		return nil
+	}
+	s.StatusQueue <- StatusUpdate{ID: info.ID, Status: StatusQueued}
+	go s.TrackPlaylistEntries(ctx, info, entries, opts)
+	return nil
+}
+
+// IsDownloaded reports whether the track has already been saved according to the download archive.
+func (s *Service) IsDownloaded(id string) bool {
+	return s.Archive.Has(id)
+}
+
+// IsInProgress reports whether the track has been queued and has not reached a final status yet.
+func (s *Service) IsInProgress(id string) bool {
+	status := make(chan string, 1)
+	s.StatusQueue <- StatusUpdate{ID: id, ShouldCallback: true, Callback: func(stat StatusUpdate) {
+		status <- stat.Status
+	}}
+	switch <-status {
+	case "", StatusComplete, StatusFailed:
+		return false
+	}
+	return true
+}
+
+// TrackPlaylistEntries queues each entry for download and reports the playlist's progress until every entry
+// has reached a final status. Every entry carries its position in the full playlist so it can be tagged with it,
+// and the tracks of a grouped playlist get their album gain once they are all done.
+func (s *Service) TrackPlaylistEntries(ctx context.Context, info *youtube_v2.PlaylistInfo, entries []string, opts DownloadOptions) {
+	id := info.ID
+	positions := make(map[string]int, len(info.Entries))
+	for i, entry := range info.Entries {
+		if _, ok := positions[entry.ID]; !ok {
+			positions[entry.ID] = i
+		}
+	}
 	for _, entry := range entries {
-		s.DownloadQueue <- entry
+		entryOpts := opts
+		entryOpts.Playlist = s.newPlaylistContext(info, positions[entry], opts)
+		s.InitiateDownload(ctx, entry, entryOpts)
 	}
 	s.StatusQueue <- StatusUpdate{ID: id, Status: StatusDownloading, PlaylistTrackCount: len(entries)}
 	for {
@@ -247,6 +388,11 @@ func (s *Service) PlaylistProcessingCallback(ctx context.Context, id string) {
 		wg.Wait()
 		s.StatusQueue <- StatusUpdate{ID: id, Status: StatusProcessing, PlaylistTrackCount: len(entries), PlaylistTrackDone: countDone}
 		if !isProcesssing {
+			if pl := s.newPlaylistContext(info, 0, opts); pl.Grouped {
+				// album gain only means something for tracks tagged as one album
+				s.WriteAlbumGain(ctx, info, pl.Title)
+			}
+			s.writePlaylistFile(ctx, id)
 			s.StatusQueue <- StatusUpdate{ID: id, Status: StatusComplete}
 			return
 		}
@@ -254,6 +400,12 @@ func (s *Service) PlaylistProcessingCallback(ctx context.Context, id string) {
 	}
 }
 
+func (s *Service) writePlaylistFile(ctx context.Context, id string) {
+	if _, err := s.PlaylistService.WritePlaylistFile(ctx, id); err != nil {
+		zaplog.ErrorC(ctx, "failed to write playlist file", zap.String("id", id), zap.Error(err))
+	}
+}
+
 func (s *Service) GetStatus(ctx context.Context, id string) (*StatusUpdate, error) {
 	var data StatusUpdate
 	wg := new(sync.WaitGroup)
@@ -293,25 +445,29 @@ func (s *Service) AcknowledgeWarning(ctx context.Context, id string) error {
 	return nil
 }
 
-func (s *Service) SaveProcessedFile(ctx context.Context, name string, url string) error {
-	zaplog.InfoC(ctx, "requesting processed file", zap.String("name", name))
+// SaveProcessedFile downloads the processed file to path, creating any folders the filename template asks for.
+func (s *Service) SaveProcessedFile(ctx context.Context, path string, url string) (string, error) {
+	zaplog.InfoC(ctx, "requesting processed file", zap.String("path", path))
 	req, err := s.HTTPClient.CreateRequest(http.MethodGet, url, nil)
 	if err != nil {
 		zaplog.ErrorC(ctx, "failed to create request", zap.Error(err))
-		return err
+		return "", err
 	}
 	resp, code, err := s.HTTPClient.DoRequest(req)
 	if err != nil {
 		zaplog.ErrorC(ctx, "failed to get processed file", zap.Error(err))
-		return fmt.Errorf("failed to get processed file: %w", err)
+		return "", fmt.Errorf("failed to get processed file: %w", err)
 	}
 	if code != http.StatusOK {
-		return fmt.Errorf("failed to get processed file, code: %d", code)
+		return "", fmt.Errorf("failed to get processed file, code: %d", code)
+	}
+	zaplog.InfoC(ctx, "retrieved processed file", zap.String("path", path))
+	zaplog.InfoC(ctx, "saving processed file", zap.String("path", path))
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
 	}
-	zaplog.InfoC(ctx, "retrieved processed file", zap.String("name", name))
-	zaplog.InfoC(ctx, "saving processed file", zap.String("name", name))
-	if err = os.Mkdir(s.Config.SaveDir, 0755); err != nil && !os.IsExist(err) {
-		panic(err)
+	if err := os.WriteFile(path, resp, 0644); err != nil {
+		return "", err
 	}
-	return os.WriteFile(fmt.Sprintf("%s/%s", s.Config.SaveDir, name), resp, 0644)
+	return path, nil
 }
@@ -0,0 +1,135 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/audiotags"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/coverart"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"go.uber.org/zap"
+)
+
+const coverSidecarName = "cover.jpg"
+
+// Meta sources recorded in the info sidecar, a provider match is recorded by the provider's name.
+const (
+	MetaSourceYoutube   = "youtube"
+	MetaSourceOverrides = "overrides"
+)
+
+// TrackInfo is the content of a track's .info.json sidecar: where the audio came from, how its metadata was
+// resolved and what was saved.
+type TrackInfo struct {
+	YoutubeID              string            `json:"youtube_id"`
+	SourceURL              string            `json:"source_url"`
+	YoutubeTitle           string            `json:"youtube_title"`
+	YoutubeAuthor          string            `json:"youtube_author"`
+	Title                  string            `json:"title"`
+	Artist                 string            `json:"artist"`
+	Album                  string            `json:"album,omitempty"`
+	MetaSource             string            `json:"meta_source"`
+	MatchScore             float64           `json:"match_score,omitempty"`
+	MatchNote              string            `json:"match_note,omitempty"`
+	ISRC                   string            `json:"isrc,omitempty"`
+	SpotifyTrackID         string            `json:"spotify_track_id,omitempty"`
+	SpotifyAlbumID         string            `json:"spotify_album_id,omitempty"`
+	MusicBrainzRecordingID string            `json:"musicbrainz_recording_id,omitempty"`
+	MusicBrainzReleaseID   string            `json:"musicbrainz_release_id,omitempty"`
+	Format                 *audiotags.Format `json:"format,omitempty"`
+	ProcessingStartedAt    time.Time         `json:"processing_started_at"`
+	SavedAt                time.Time         `json:"saved_at"`
+}
+
+// WriteSidecars writes the enabled cover and info sidecars for a saved track. A failing sidecar is logged and does
+// not fail the download, the audio is already saved.
+func (s *Service) WriteSidecars(ctx context.Context, audioPath string, trackMeta *meta.TrackMeta, youtubeMeta meta.TrackMeta, started time.Time) {
+	if s.Config.CoverSidecar {
+		if err := s.WriteCoverSidecar(ctx, audioPath, trackMeta); err != nil {
+			zaplog.ErrorC(ctx, "failed to write cover sidecar", zap.String("id", trackMeta.ID), zap.Error(err))
+		}
+	}
+	if s.Config.InfoSidecar {
+		if err := s.WriteInfoSidecar(audioPath, trackMeta, youtubeMeta, started); err != nil {
+			zaplog.ErrorC(ctx, "failed to write info sidecar", zap.String("id", trackMeta.ID), zap.Error(err))
+		}
+	}
+}
+
+// WriteCoverSidecar saves the track's cover as cover.jpg in its folder unless the folder already has one. Tracks
+// saved straight into the save dir are skipped, one cover cannot stand for every album there.
+func (s *Service) WriteCoverSidecar(ctx context.Context, audioPath string, trackMeta *meta.TrackMeta) error {
+	dir := filepath.Dir(audioPath)
+	if trackMeta.CoverArtURL == "" || filepath.Clean(dir) == filepath.Clean(s.Config.SaveDir) {
+		return nil
+	}
+	coverPath := filepath.Join(dir, coverSidecarName)
+	if _, err := os.Stat(coverPath); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	data, err := coverart.Fetch(ctx, s.HTTPClient.Client, trackMeta.CoverArtURL, s.Config.CoverArtMaxSize)
+	if err != nil {
+		return err
+	}
+	data, err = coverart.Process(data, coverart.Options{MaxSize: s.Config.CoverArtMaxSize, MaxBytes: s.Config.CoverArtMaxBytes})
+	if err != nil {
+		return err
+	}
+	zaplog.InfoC(ctx, "writing cover sidecar", zap.String("path", coverPath))
+	return os.WriteFile(coverPath, data, 0644)
+}
+
+// WriteInfoSidecar writes the track's .info.json next to the saved audio file.
+func (s *Service) WriteInfoSidecar(audioPath string, trackMeta *meta.TrackMeta, youtubeMeta meta.TrackMeta, started time.Time) error {
+	info := TrackInfo{
+		YoutubeID:              trackMeta.ID,
+		SourceURL:              fmt.Sprintf("https://www.youtube.com/watch?v=%s", trackMeta.ID),
+		YoutubeTitle:           youtubeMeta.Title,
+		YoutubeAuthor:          youtubeMeta.Artist,
+		Title:                  trackMeta.Title,
+		Artist:                 trackMeta.Artist,
+		Album:                  trackMeta.Album,
+		MetaSource:             metaSource(trackMeta),
+		MatchScore:             trackMeta.MatchScore,
+		MatchNote:              trackMeta.MatchNote,
+		ISRC:                   trackMeta.ISRC,
+		SpotifyTrackID:         trackMeta.SpotifyTrackID,
+		SpotifyAlbumID:         trackMeta.SpotifyAlbumID,
+		MusicBrainzRecordingID: trackMeta.MusicBrainzRecordingID,
+		MusicBrainzReleaseID:   trackMeta.MusicBrainzReleaseID,
+		ProcessingStartedAt:    started,
+		SavedAt:                time.Now(),
+	}
+	format, err := audiotags.ReadFormat(audioPath)
+	if err != nil {
+		return fmt.Errorf("failed to read audio format: %w", err)
+	}
+	info.Format = format
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	infoPath := strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".info.json"
+	return os.WriteFile(infoPath, data, 0644)
+}
+
+// metaSource tells where the saved metadata came from: the request's overrides, a provider match or YouTube.
+func metaSource(trackMeta *meta.TrackMeta) string {
+	if trackMeta.MatchNote == meta.MatchNoteOverridden {
+		return MetaSourceOverrides
+	}
+	if trackMeta.Provider != "" {
+		return trackMeta.Provider
+	}
+	return MetaSourceYoutube
+}
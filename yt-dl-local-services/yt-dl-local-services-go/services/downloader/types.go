@@ -2,20 +2,23 @@ package downloader
 
 import (
 	"context"
+	"sync"
 
 	"github.com/gcottom/semaphore"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/http_client"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/youtube_v2"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/library"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/lyrics"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
-	spotifyauth "github.com/zmb3/spotify/v2/auth"
-	"golang.org/x/oauth2/clientcredentials"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/playlists"
 )
 
 type DownloaderService interface {
-	InitiateDownload(ctx context.Context, id string) error
+	InitiateDownload(ctx context.Context, id string, opts DownloadOptions) error
 	GetStatus(ctx context.Context, id string) (*StatusUpdate, error)
 	AcknowledgeWarning(ctx context.Context, id string) error
+	SubmitReview(ctx context.Context, id string, decision ReviewDecision) error
 }
 
 type Service struct {
@@ -23,33 +26,65 @@ type Service struct {
 	HTTPClient        *http_client.HTTPClient
 	DownloadLimiter   *semaphore.Semaphore
 	SaveFileLimiter   *semaphore.Semaphore
-	DownloadQueue     chan string
+	DownloadQueue     chan DownloadRequest
 	StatusQueue       chan StatusUpdate
 	StatusMap         map[string]StatusUpdate
 	YoutubeClient     youtube_v2.YoutubeClient
 	MetaServiceClient *meta.Service
+	LyricsService     *lyrics.Service
+	Archive           *Archive
+	LibraryService    *library.Service
+	PlaylistService   *playlists.Service
+
+	reviewMu sync.Mutex
+	Reviews  map[string]*Review
+
+	// saving holds the paths of files being saved and the video each one is for
+	savingMu sync.Mutex
+	saving   map[string]string
 }
 
-func NewDownloaderService(cfg *config.Config, httpClient *http_client.HTTPClient) *Service {
-	return &Service{
-		Config:          cfg,
-		HTTPClient:      httpClient,
-		DownloadLimiter: semaphore.NewSemaphore(cfg.ConcurrentDownloads),
-		SaveFileLimiter: semaphore.NewSemaphore(cfg.ConcurrentDownloads),
-		DownloadQueue:   make(chan string, 5000),
-		StatusQueue:     make(chan StatusUpdate, 5000),
-		StatusMap:       make(map[string]StatusUpdate),
-		YoutubeClient:   youtube_v2.NewYoutubeClient(cfg, httpClient),
-		MetaServiceClient: &meta.Service{
-			Config:     cfg,
-			HTTPClient: httpClient,
-			SpotifyConfig: &clientcredentials.Config{
-				ClientID:     cfg.SpotifyClientID,
-				ClientSecret: cfg.SpotifyClientSecret,
-				TokenURL:     spotifyauth.TokenURL,
-			},
-		},
+func NewDownloaderService(cfg *config.Config, httpClient *http_client.HTTPClient, libraryService *library.Service, playlistService *playlists.Service) (*Service, error) {
+	archive, err := NewArchive(cfg.StateDir)
+	if err != nil {
+		return nil, err
 	}
+	metaService, err := meta.NewMetaService(cfg, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	youtubeClient := youtube_v2.NewYoutubeClient(cfg, httpClient)
+	return &Service{
+		Config:            cfg,
+		HTTPClient:        httpClient,
+		DownloadLimiter:   semaphore.NewSemaphore(cfg.ConcurrentDownloads),
+		SaveFileLimiter:   semaphore.NewSemaphore(cfg.ConcurrentDownloads),
+		DownloadQueue:     make(chan DownloadRequest, 5000),
+		StatusQueue:       make(chan StatusUpdate, 5000),
+		StatusMap:         make(map[string]StatusUpdate),
+		YoutubeClient:     youtubeClient,
+		MetaServiceClient: metaService,
+		LyricsService:     lyrics.NewLyricsService(cfg, httpClient, youtubeClient),
+		Archive:           archive,
+		LibraryService:    libraryService,
+		PlaylistService:   playlistService,
+		saving:            make(map[string]string),
+		Reviews:           make(map[string]*Review),
+	}, nil
+}
+
+// DownloadOptions are the per request settings that travel with a download. Playlist entries inherit the
+// options of the playlist they were queued from.
+type DownloadOptions struct {
+	Template        string           `json:"template,omitempty"`
+	PlaylistAsAlbum *bool            `json:"playlist_as_album,omitempty"`
+	Playlist        *PlaylistContext `json:"playlist,omitempty"`
+	Overrides       *meta.Overrides  `json:"overrides,omitempty"`
+}
+
+type DownloadRequest struct {
+	ID      string
+	Options DownloadOptions
 }
 
 type StatusUpdate struct {
@@ -62,13 +97,15 @@ type StatusUpdate struct {
 	Warning            string             `json:"warning,omitempty"`
 	TrackArtist        string             `json:"track_artist,omitempty"`
 	TrackTitle         string             `json:"track_title,omitempty"`
+	Review             *Review            `json:"review,omitempty"`
 }
 
 type ProcessingStatus struct {
-	ID       string `json:"id"`
-	Status   string `json:"status"`
-	FileURL  string `json:"url"`
-	FileName string `json:"file_name"`
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	FileURL     string `json:"url"`
+	FileName    string `json:"file_name"`
+	Fingerprint string `json:"fingerprint"`
 }
 
 const (
@@ -79,4 +116,5 @@ const (
 	StatusFailed      = "failed"
 	StatusWarning     = "warning"
 	StatusWarningAck  = "warning_ack"
+	StatusNeedsReview = "needs_review"
 )
@@ -0,0 +1,143 @@
+package library
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/audiotags"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/fingerprint"
+	"go.uber.org/zap"
+)
+
+// SetFingerprint stores the acoustic fingerprint of an indexed file and the file it was found to duplicate, if any.
+func (s *Service) SetFingerprint(ctx context.Context, path string, fp string, duplicateOf string) error {
+	key, err := s.relativePath(path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.Entries[key]
+	if !ok {
+		return nil
+	}
+	entry.Fingerprint = fp
+	entry.DuplicateOf = duplicateOf
+	return s.save()
+}
+
+// FindDuplicate returns the indexed file that sounds the same as the given fingerprint and how similar it is.
+// Files downloaded from the same video are not duplicates, they are the same download saved again.
+func (s *Service) FindDuplicate(fp string, youtubeID string) (*Entry, float64, error) {
+	probe, err := fingerprint.Decode(fp)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	index := fingerprint.NewIndex()
+	for key, entry := range s.Entries {
+		if entry.Fingerprint == "" || youtubeID != "" && entry.YoutubeID == youtubeID {
+			continue
+		}
+		if fp, err := fingerprint.Decode(entry.Fingerprint); err == nil {
+			index.Add(key, fp)
+		}
+	}
+	matches := index.Matches(probe, s.Config.DuplicateThreshold)
+	if len(matches) == 0 {
+		return nil, 0, nil
+	}
+	out := *s.Entries[matches[0].Key]
+	return &out, matches[0].Similarity, nil
+}
+
+// Duplicates groups the indexed files that are acoustically the same. Each group lists its files with the highest
+// bitrate first, that is the copy worth keeping.
+func (s *Service) Duplicates(ctx context.Context) (*DuplicateReport, error) {
+	s.mu.RLock()
+	index := fingerprint.NewIndex()
+	prints := make(map[string]fingerprint.Fingerprint)
+	entries := make(map[string]Entry)
+	for key, entry := range s.Entries {
+		if entry.Fingerprint == "" {
+			continue
+		}
+		if fp, err := fingerprint.Decode(entry.Fingerprint); err == nil {
+			index.Add(key, fp)
+			prints[key] = fp
+			entries[key] = *entry
+		}
+	}
+	s.mu.RUnlock()
+
+	// files are grouped with everything they match, directly or through another file of the group
+	parent := make(map[string]string, len(prints))
+	var root func(key string) string
+	root = func(key string) string {
+		if parent[key] == "" || parent[key] == key {
+			return key
+		}
+		parent[key] = root(parent[key])
+		return parent[key]
+	}
+	similarity := make(map[string]float64)
+	for key, fp := range prints {
+		for _, match := range index.Matches(fp, s.Config.DuplicateThreshold) {
+			if match.Key == key {
+				continue
+			}
+			a, b := root(key), root(match.Key)
+			if a != b {
+				parent[b] = a
+			}
+			// sampling the probe can find a match in one direction only, both files are recorded
+			similarity[key] = max(similarity[key], match.Similarity)
+			similarity[match.Key] = max(similarity[match.Key], match.Similarity)
+		}
+	}
+	groups := make(map[string][]string)
+	for key := range similarity {
+		groups[root(key)] = append(groups[root(key)], key)
+	}
+
+	report := &DuplicateReport{Groups: make([]DuplicateGroup, 0, len(groups))}
+	for _, keys := range groups {
+		if len(keys) < 2 {
+			continue
+		}
+		group := DuplicateGroup{Files: make([]DuplicateFile, 0, len(keys))}
+		for _, key := range keys {
+			entry := entries[key]
+			file := DuplicateFile{
+				Path:       entry.Path,
+				YoutubeID:  entry.YoutubeID,
+				Title:      entry.Title,
+				Artist:     entry.Artist,
+				Album:      entry.Album,
+				Size:       entry.Size,
+				Similarity: similarity[key],
+			}
+			if format, err := audiotags.ReadFormat(filepath.Join(s.Config.SaveDir, filepath.FromSlash(entry.Path))); err == nil {
+				file.Container = format.Container
+				file.BitrateKbps = format.BitrateKbps
+				file.DurationMs = format.DurationMs
+			} else {
+				zaplog.WarnC(ctx, "failed to read audio format", zap.String("path", entry.Path), zap.Error(err))
+			}
+			group.Files = append(group.Files, file)
+		}
+		sort.Slice(group.Files, func(i, j int) bool {
+			if group.Files[i].BitrateKbps != group.Files[j].BitrateKbps {
+				return group.Files[i].BitrateKbps > group.Files[j].BitrateKbps
+			}
+			return group.Files[i].Path < group.Files[j].Path
+		})
+		report.Groups = append(report.Groups, group)
+		report.Files += len(group.Files)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool { return report.Groups[i].Files[0].Path < report.Groups[j].Files[0].Path })
+	return report, nil
+}
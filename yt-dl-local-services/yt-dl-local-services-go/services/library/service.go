@@ -0,0 +1,333 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/audiotags"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/filestore"
+	"go.uber.org/zap"
+)
+
+// Scan walks the save dir and brings the index in line with what is on disk. Files whose size and modification
+// time are unchanged are not re-read, so a rescan of a large library only touches new or edited files.
+func (s *Service) Scan(ctx context.Context) (*ScanResult, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	zaplog.InfoC(ctx, "scanning library", zap.String("saveDir", s.Config.SaveDir))
+
+	s.mu.RLock()
+	existing := make(map[string]Entry, len(s.Entries))
+	for key, entry := range s.Entries {
+		existing[key] = *entry
+	}
+	s.mu.RUnlock()
+
+	result := &ScanResult{}
+	found := make(map[string]*Entry)
+	err := filepath.WalkDir(s.Config.SaveDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == s.Config.SaveDir {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || !audiotags.IsAudioFile(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		key, err := s.relativePath(path)
+		if err != nil {
+			return err
+		}
+		if old, ok := existing[key]; ok && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) {
+			found[key] = &old
+			return nil
+		}
+		entry, err := s.readEntry(path, key, info)
+		if err != nil {
+			zaplog.WarnC(ctx, "failed to read tags, skipping file", zap.String("path", path), zap.Error(err))
+			return nil
+		}
+		if old, ok := existing[key]; ok {
+			entry.AddedAt = old.AddedAt
+			entry.YoutubeID = old.YoutubeID
+			// retagging rewrites the file but not its audio
+			entry.Fingerprint = old.Fingerprint
+			entry.DuplicateOf = old.DuplicateOf
+			result.Updated++
+		} else {
+			result.Added++
+		}
+		found[key] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan save dir: %w", err)
+	}
+	for key := range existing {
+		if _, ok := found[key]; !ok {
+			result.Removed++
+		}
+	}
+	result.Total = len(found)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// entries added by completed jobs while the walk was running are kept, the next scan will validate them
+	for key, entry := range s.Entries {
+		if scanned, ok := found[key]; ok {
+			if scanned.YoutubeID == "" {
+				scanned.YoutubeID = entry.YoutubeID
+			}
+			if scanned.Fingerprint == "" {
+				scanned.Fingerprint = entry.Fingerprint
+				scanned.DuplicateOf = entry.DuplicateOf
+			}
+			continue
+		}
+		if _, ok := existing[key]; !ok {
+			found[key] = entry
+		}
+	}
+	s.Entries = found
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	zaplog.InfoC(ctx, "library scan complete", zap.Int("added", result.Added), zap.Int("updated", result.Updated), zap.Int("removed", result.Removed), zap.Int("total", result.Total))
+	return result, nil
+}
+
+// AddFile indexes a single file, it is called when a download job saves its output so the index does not have to
+// wait for the next scan.
+func (s *Service) AddFile(ctx context.Context, path string, youtubeID string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	key, err := s.relativePath(path)
+	if err != nil {
+		return err
+	}
+	entry, err := s.readEntry(path, key, info)
+	if err != nil {
+		return err
+	}
+	entry.YoutubeID = youtubeID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.Entries[key]; ok {
+		entry.AddedAt = old.AddedAt
+		entry.Fingerprint = old.Fingerprint
+		entry.DuplicateOf = old.DuplicateOf
+	}
+	s.Entries[key] = entry
+	zaplog.InfoC(ctx, "library entry added", zap.String("path", key), zap.String("id", youtubeID))
+	return s.save()
+}
+
+// GetEntry returns the index entry for an absolute path inside the save dir.
+func (s *Service) GetEntry(path string) (*Entry, bool) {
+	key, err := s.relativePath(path)
+	if err != nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.Entries[key]
+	if !ok {
+		return nil, false
+	}
+	out := *entry
+	return &out, true
+}
+
+// FindByYoutubeID returns the indexed file that was downloaded from the given video, if any.
+func (s *Service) FindByYoutubeID(id string) (*Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, entry := range s.Entries {
+		if entry.YoutubeID == id {
+			out := *entry
+			return &out, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Service) Query(ctx context.Context, query Query) (*Page, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = defaultPageSize
+	}
+	if query.PageSize > maxPageSize {
+		query.PageSize = maxPageSize
+	}
+	if query.Sort == "" {
+		query.Sort = SortArtist
+	}
+	if query.Order == "" {
+		query.Order = OrderAsc
+	}
+	if query.Order != OrderAsc && query.Order != OrderDesc {
+		return nil, fmt.Errorf("invalid order %q, expected %q or %q", query.Order, OrderAsc, OrderDesc)
+	}
+	less, err := sortFunc(query.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	items := make([]Entry, 0)
+	for _, entry := range s.Entries {
+		if matches(entry, query) {
+			items = append(items, *entry)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if query.Order == OrderDesc {
+			return less(items[j], items[i])
+		}
+		return less(items[i], items[j])
+	})
+	page := &Page{Total: len(items), Page: query.Page, PageSize: query.PageSize, Items: make([]Entry, 0)}
+	start := (query.Page - 1) * query.PageSize
+	if start < len(items) {
+		end := min(start+query.PageSize, len(items))
+		page.Items = items[start:end]
+	}
+	return page, nil
+}
+
+// Find returns every entry matching the query's filters sorted by path, the query's sort and paging are ignored.
+func (s *Service) Find(query Query) []Entry {
+	s.mu.RLock()
+	items := make([]Entry, 0)
+	for _, entry := range s.Entries {
+		if matches(entry, query) {
+			items = append(items, *entry)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items
+}
+
+// Scanner rescans the save dir on startup and then every library_scan_interval_minutes.
+func (s *Service) Scanner() {
+	ctx := context.Background()
+	for {
+		if _, err := s.Scan(ctx); err != nil {
+			zaplog.ErrorC(ctx, "library scan failed", zap.Error(err))
+		}
+		time.Sleep(time.Duration(s.Config.LibraryScanIntervalMinutes) * time.Minute)
+	}
+}
+
+func (s *Service) readEntry(path string, key string, info fs.FileInfo) (*Entry, error) {
+	tags, err := audiotags.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{
+		Path:    key,
+		Title:   tags.Title,
+		Artist:  tags.Artist,
+		Album:   tags.Album,
+		Genre:   tags.Genre,
+		Moods:   tags.Moods,
+		Styles:  tags.Styles,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		AddedAt: time.Now(),
+	}, nil
+}
+
+func (s *Service) relativePath(path string) (string, error) {
+	rel, err := filepath.Rel(s.Config.SaveDir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// save persists the index, the caller must hold s.mu.
+func (s *Service) save() error {
+	return filestore.Save(s.path, s.Entries)
+}
+
+func matches(entry *Entry, query Query) bool {
+	if !containsFold(entry.Artist, query.Artist) || !containsFold(entry.Album, query.Album) ||
+		!containsFold(entry.Title, query.Title) || !containsFold(entry.Genre, query.Genre) {
+		return false
+	}
+	// moods and styles are whole tags, "chill" should not match "chillout"
+	if query.Mood != "" && !hasFold(entry.Moods, query.Mood) || query.Style != "" && !hasFold(entry.Styles, query.Style) {
+		return false
+	}
+	if query.Search == "" {
+		return true
+	}
+	return containsFold(entry.Artist, query.Search) || containsFold(entry.Album, query.Search) ||
+		containsFold(entry.Title, query.Search) || containsFold(entry.Genre, query.Search)
+}
+
+func containsFold(value string, substr string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(substr))
+}
+
+func hasFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortFunc(field string) (func(a, b Entry) bool, error) {
+	byString := func(get func(Entry) string) func(a, b Entry) bool {
+		return func(a, b Entry) bool {
+			av, bv := strings.ToLower(get(a)), strings.ToLower(get(b))
+			if av != bv {
+				return av < bv
+			}
+			return a.Path < b.Path
+		}
+	}
+	switch field {
+	case SortArtist:
+		return byString(func(e Entry) string { return e.Artist }), nil
+	case SortAlbum:
+		return byString(func(e Entry) string { return e.Album }), nil
+	case SortTitle:
+		return byString(func(e Entry) string { return e.Title }), nil
+	case SortGenre:
+		return byString(func(e Entry) string { return e.Genre }), nil
+	case SortPath:
+		return byString(func(e Entry) string { return e.Path }), nil
+	case SortAddedAt:
+		return func(a, b Entry) bool {
+			if !a.AddedAt.Equal(b.AddedAt) {
+				return a.AddedAt.Before(b.AddedAt)
+			}
+			return a.Path < b.Path
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid sort field %q", field)
+}
@@ -0,0 +1,124 @@
+package library
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/filestore"
+)
+
+type LibraryService interface {
+	Query(ctx context.Context, query Query) (*Page, error)
+	Scan(ctx context.Context) (*ScanResult, error)
+	Duplicates(ctx context.Context) (*DuplicateReport, error)
+}
+
+type Service struct {
+	Config *config.Config
+
+	mu      sync.RWMutex
+	scanMu  sync.Mutex
+	path    string
+	Entries map[string]*Entry
+}
+
+func NewLibraryService(cfg *config.Config) (*Service, error) {
+	s := &Service{
+		Config:  cfg,
+		path:    filepath.Join(cfg.StateDir, "library.json"),
+		Entries: make(map[string]*Entry),
+	}
+	if err := filestore.Load(s.path, &s.Entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Entry is a single audio file in the save dir. Path is relative to the save dir and always uses forward slashes.
+// Fingerprint is only known for files the downloader saved, DuplicateOf is the path of the file it sounded the
+// same as when it was saved. Moods and Styles are the genre classifier's tags, most likely first.
+type Entry struct {
+	Path        string    `json:"path"`
+	YoutubeID   string    `json:"youtube_id,omitempty"`
+	Title       string    `json:"title"`
+	Artist      string    `json:"artist"`
+	Album       string    `json:"album,omitempty"`
+	Genre       string    `json:"genre,omitempty"`
+	Moods       []string  `json:"moods,omitempty"`
+	Styles      []string  `json:"styles,omitempty"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	AddedAt     time.Time `json:"added_at"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	DuplicateOf string    `json:"duplicate_of,omitempty"`
+}
+
+type Query struct {
+	Artist   string `form:"artist" json:"artist,omitempty"`
+	Album    string `form:"album" json:"album,omitempty"`
+	Title    string `form:"title" json:"title,omitempty"`
+	Genre    string `form:"genre" json:"genre,omitempty"`
+	Mood     string `form:"mood" json:"mood,omitempty"`
+	Style    string `form:"style" json:"style,omitempty"`
+	Search   string `form:"q" json:"q,omitempty"`
+	Sort     string `form:"sort" json:"sort,omitempty"`
+	Order    string `form:"order" json:"order,omitempty"`
+	Page     int    `form:"page" json:"page,omitempty"`
+	PageSize int    `form:"page_size" json:"page_size,omitempty"`
+}
+
+type Page struct {
+	Total    int     `json:"total"`
+	Page     int     `json:"page"`
+	PageSize int     `json:"page_size"`
+	Items    []Entry `json:"items"`
+}
+
+// DuplicateReport lists the groups of library files that are acoustically the same.
+type DuplicateReport struct {
+	Groups []DuplicateGroup `json:"groups"`
+	Files  int              `json:"files"`
+}
+
+type DuplicateGroup struct {
+	Files []DuplicateFile `json:"files"`
+}
+
+// DuplicateFile is a file of a duplicate group, Similarity is how closely it matches the closest other file.
+type DuplicateFile struct {
+	Path        string  `json:"path"`
+	YoutubeID   string  `json:"youtube_id,omitempty"`
+	Title       string  `json:"title"`
+	Artist      string  `json:"artist"`
+	Album       string  `json:"album,omitempty"`
+	Container   string  `json:"container,omitempty"`
+	BitrateKbps int     `json:"bitrate_kbps,omitempty"`
+	DurationMs  int     `json:"duration_ms,omitempty"`
+	Size        int64   `json:"size"`
+	Similarity  float64 `json:"similarity"`
+}
+
+type ScanResult struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+	Total   int `json:"total"`
+}
+
+const (
+	SortArtist  = "artist"
+	SortAlbum   = "album"
+	SortTitle   = "title"
+	SortGenre   = "genre"
+	SortAddedAt = "added_at"
+	SortPath    = "path"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
@@ -0,0 +1,135 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/retry"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/textnorm"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"go.uber.org/zap"
+)
+
+// GetLyrics looks up lyrics for a resolved track. Synced lyrics from LRCLIB are preferred, then the video's
+// uploaded captions, then plain LRCLIB lyrics. A nil result means no lyrics were found.
+func (s *Service) GetLyrics(ctx context.Context, trackMeta *meta.TrackMeta) (*Lyrics, error) {
+	if s.Config.DisableLyrics {
+		return nil, nil
+	}
+	var plain string
+	res, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s.SearchLRCLIB, ctx, trackMeta.Title, trackMeta.Artist)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to search lrclib", zap.String("id", trackMeta.ID), zap.Error(err))
+	} else if match := bestLRCLIBMatch(trackMeta, res[0].([]LRCLIBTrack), time.Duration(s.Config.MetadataDurationToleranceSeconds)*time.Second); match != nil {
+		if match.SyncedLyrics != "" {
+			return &Lyrics{Plain: match.PlainLyrics, Synced: match.SyncedLyrics, Source: SourceLRCLIB}, nil
+		}
+		plain = match.PlainLyrics
+	}
+	captions, err := s.YoutubeClient.GetCaptions(ctx, trackMeta.ID)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to get youtube captions", zap.String("id", trackMeta.ID), zap.Error(err))
+	} else if captions != "" {
+		return &Lyrics{Plain: PlainFromLRC(captions), Synced: captions, Source: SourceYoutubeCaptions}, nil
+	}
+	if plain != "" {
+		return &Lyrics{Plain: plain, Source: SourceLRCLIB}, nil
+	}
+	zaplog.InfoC(ctx, "no lyrics found", zap.String("id", trackMeta.ID))
+	return nil, nil
+}
+
+func (s *Service) SearchLRCLIB(ctx context.Context, title string, artist string) ([]LRCLIBTrack, error) {
+	query := url.Values{}
+	query.Set("track_name", title)
+	query.Set("artist_name", artist)
+	req, err := s.HTTPClient.CreateRequest(http.MethodGet, fmt.Sprintf("%s/api/search?%s", strings.TrimRight(s.Config.LyricsAPIURL, "/"), query.Encode()), nil)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to create lyrics request", zap.Error(err))
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "yt-dl-3-hybrid (https://github.com/gcottom/yt-dl-3-hybrid)")
+	res, status, err := s.HTTPClient.DoRequest(req)
+	if err != nil {
+		zaplog.ErrorC(ctx, "error while sending lyrics request", zap.Error(err))
+		return nil, err
+	}
+	if status != http.StatusOK {
+		zaplog.ErrorC(ctx, "lyrics request failed", zap.Int("code", status))
+		return nil, fmt.Errorf("lyrics request failed: %d", status)
+	}
+	var tracks []LRCLIBTrack
+	if err := json.Unmarshal(res, &tracks); err != nil {
+		zaplog.ErrorC(ctx, "failed to unmarshal lyrics response", zap.Error(err))
+		return nil, err
+	}
+	return tracks, nil
+}
+
+// bestLRCLIBMatch returns the first result for the track with synced lyrics, or the first with plain lyrics when
+// none are synced. LRCLIB's search is fuzzy, results of other songs or of versions whose length differs by more
+// than the tolerance are skipped.
+func bestLRCLIBMatch(trackMeta *meta.TrackMeta, tracks []LRCLIBTrack, tolerance time.Duration) *LRCLIBTrack {
+	var plain *LRCLIBTrack
+	for i, track := range tracks {
+		if track.Instrumental || !isSameTrack(trackMeta, track, tolerance) {
+			continue
+		}
+		if track.SyncedLyrics != "" {
+			return &tracks[i]
+		}
+		if plain == nil && track.PlainLyrics != "" {
+			plain = &tracks[i]
+		}
+	}
+	return plain
+}
+
+// isSameTrack reports whether a search result is the track. Titles and artists match when one contains the other
+// once folded, so "Song (feat. B)" by "A, B" still matches "Song" by "A". The length is only compared when both
+// are known.
+func isSameTrack(trackMeta *meta.TrackMeta, track LRCLIBTrack, tolerance time.Duration) bool {
+	if !containsEither(matchText(trackMeta.Title), matchText(track.TrackName)) ||
+		!containsEither(matchText(trackMeta.Artist), matchText(track.ArtistName)) {
+		return false
+	}
+	if trackMeta.DurationMs <= 0 || track.Duration <= 0 {
+		return true
+	}
+	diff := time.Duration(trackMeta.DurationMs)*time.Millisecond - time.Duration(track.Duration*float64(time.Second))
+	return diff.Abs() <= tolerance
+}
+
+func containsEither(a string, b string) bool {
+	return a != "" && b != "" && (strings.Contains(a, b) || strings.Contains(b, a))
+}
+
+// matchText folds a title or artist and reduces it to letters and digits separated by single spaces.
+func matchText(str string) string {
+	words := strings.FieldsFunc(textnorm.Fold(str), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	})
+	return strings.Join(words, " ")
+}
+
+var lrcTimestamp = regexp.MustCompile(`^(\[[^\]]*\])+`)
+
+// PlainFromLRC strips the timestamps and tags from LRC lyrics.
+func PlainFromLRC(lrc string) string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(lrc, "\n") {
+		line = strings.TrimSpace(lrcTimestamp.ReplaceAllString(strings.TrimSpace(line), ""))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
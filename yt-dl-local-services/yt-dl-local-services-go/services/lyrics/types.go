@@ -0,0 +1,52 @@
+package lyrics
+
+import (
+	"context"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/http_client"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/youtube_v2"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+)
+
+type LyricsService interface {
+	GetLyrics(ctx context.Context, trackMeta *meta.TrackMeta) (*Lyrics, error)
+}
+
+type Service struct {
+	Config        *config.Config
+	HTTPClient    *http_client.HTTPClient
+	YoutubeClient youtube_v2.YoutubeClient
+}
+
+func NewLyricsService(cfg *config.Config, httpClient *http_client.HTTPClient, youtubeClient youtube_v2.YoutubeClient) *Service {
+	return &Service{
+		Config:        cfg,
+		HTTPClient:    httpClient,
+		YoutubeClient: youtubeClient,
+	}
+}
+
+// Lyrics holds the plain text lyrics of a track and, when known, the time-synced version in LRC format.
+type Lyrics struct {
+	Plain  string `json:"plain,omitempty"`
+	Synced string `json:"synced,omitempty"`
+	Source string `json:"source"`
+}
+
+const (
+	SourceLRCLIB          = "lrclib"
+	SourceYoutubeCaptions = "youtube_captions"
+)
+
+// LRCLIBTrack is a single result of the LRCLIB search API.
+type LRCLIBTrack struct {
+	ID           int     `json:"id"`
+	TrackName    string  `json:"trackName"`
+	ArtistName   string  `json:"artistName"`
+	AlbumName    string  `json:"albumName"`
+	Duration     float64 `json:"duration"`
+	Instrumental bool    `json:"instrumental"`
+	PlainLyrics  string  `json:"plainLyrics"`
+	SyncedLyrics string  `json:"syncedLyrics"`
+}
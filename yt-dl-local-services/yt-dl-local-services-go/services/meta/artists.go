@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"strings"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/artists"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/textnorm"
+)
+
+// spotifyFeatured returns the credited artists that Spotify's title names as featured, Spotify credits features
+// side by side with the main artist and only the title tells them apart.
+func spotifyFeatured(title string, credited []string) []string {
+	_, named := artists.ParseTitle(title)
+	featured := make([]string, 0)
+	for _, name := range named {
+		for _, artist := range credited {
+			if textnorm.Equal(name, artist) {
+				featured = append(featured, artist)
+			}
+		}
+	}
+	return featured
+}
+
+// ApplyArtistStyle fills Artists with every credited artist, primary artists first, and moves the featured
+// artists into the title or the artist tag as featured_artist_style asks. An artist credit parsed from a string
+// keeps its primary artists as written, "Mumford & Sons" is split for Artists but never tagged "Mumford, Sons".
+func (s *Service) ApplyArtistStyle(trackMeta *TrackMeta) {
+	title, named := artists.ParseTitle(trackMeta.Title)
+	credit := artists.Parse(trackMeta.Artist)
+	whole, _ := artists.Split(trackMeta.Artist)
+	if len(trackMeta.Artists) > 0 {
+		whole = ""
+		credit = artists.Credit{}
+		for _, artist := range trackMeta.Artists {
+			if !containsArtist(trackMeta.FeaturedArtists, artist) {
+				credit.Primary = append(credit.Primary, artist)
+			}
+		}
+		credit.AddFeatured(trackMeta.FeaturedArtists...)
+	}
+	credit.AddFeatured(named...)
+	if len(credit.Primary) == 0 {
+		return
+	}
+	trackMeta.Artists = credit.All()
+	trackMeta.FeaturedArtists = credit.Featured
+	display := credit
+	if whole != "" && len(artists.Parse(whole).Primary) == len(credit.Primary) {
+		// no primary artist was moved to the featured ones, the credit is tagged as written
+		display.Primary = []string{whole}
+	}
+	switch s.Config.FeaturedArtistStyle {
+	case "title":
+		trackMeta.Title = artists.FormatTitle(title, display.Featured, s.Config.ArtistSeparator)
+		trackMeta.Artist = strings.Join(display.Primary, s.Config.ArtistSeparator)
+	case "artist":
+		trackMeta.Title = title
+		trackMeta.Artist = display.Format(s.Config.ArtistSeparator)
+	}
+}
+
+func containsArtist(names []string, name string) bool {
+	for _, n := range names {
+		if textnorm.Equal(n, name) {
+			return true
+		}
+	}
+	return false
+}
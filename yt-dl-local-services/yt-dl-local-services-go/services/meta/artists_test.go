@@ -0,0 +1,53 @@
+package meta
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+)
+
+func TestApplyArtistStyle(t *testing.T) {
+	tests := []struct {
+		style   string
+		track   TrackMeta
+		title   string
+		artist  string
+		artists []string
+	}{
+		{"title", TrackMeta{Title: "Little Lion Man", Artist: "Mumford & Sons"}, "Little Lion Man", "Mumford & Sons", []string{"Mumford", "Sons"}},
+		{"artist", TrackMeta{Title: "September", Artist: "Earth, Wind & Fire"}, "September", "Earth, Wind & Fire", []string{"Earth", "Wind", "Fire"}},
+		{"title", TrackMeta{Title: "Song", Artist: "Chase & Status feat. A"}, "Song (feat. A)", "Chase & Status", []string{"Chase", "Status", "A"}},
+		{"artist", TrackMeta{Title: "Song (feat. A)", Artist: "Chase & Status"}, "Song", "Chase & Status feat. A", []string{"Chase", "Status", "A"}},
+		{"title", TrackMeta{Title: "Song (feat. B)", Artist: "A & B"}, "Song (feat. B)", "A", []string{"A", "B"}},
+		{"title", TrackMeta{Title: "Song", Artist: "A, B", Artists: []string{"A", "B"}}, "Song", "A, B", []string{"A", "B"}},
+		{"keep", TrackMeta{Title: "Song (feat. A)", Artist: "Mumford & Sons"}, "Song (feat. A)", "Mumford & Sons", []string{"Mumford", "Sons", "A"}},
+	}
+	for _, tt := range tests {
+		s := &Service{Config: &config.Config{FeaturedArtistStyle: tt.style, ArtistSeparator: ", "}}
+		track := tt.track
+		s.ApplyArtistStyle(&track)
+		if track.Title != tt.title || track.Artist != tt.artist || !reflect.DeepEqual(track.Artists, tt.artists) {
+			t.Errorf("%s %q by %q = %q by %q %v, want %q by %q %v", tt.style, tt.track.Title, tt.track.Artist,
+				track.Title, track.Artist, track.Artists, tt.title, tt.artist, tt.artists)
+		}
+	}
+}
+
+func TestOverridesApplyArtist(t *testing.T) {
+	tests := []struct {
+		artist   string
+		artists  []string
+		featured []string
+	}{
+		{"Mumford & Sons", []string{"Mumford & Sons"}, nil},
+		{"Chase & Status feat. A & B", []string{"Chase & Status", "A", "B"}, []string{"A", "B"}},
+	}
+	for _, tt := range tests {
+		track := TrackMeta{Artist: "Someone"}
+		(&Overrides{Artist: tt.artist}).Apply(&track)
+		if track.Artist != tt.artist || !reflect.DeepEqual(track.Artists, tt.artists) || !reflect.DeepEqual(track.FeaturedArtists, tt.featured) {
+			t.Errorf("Apply(%q) = %q %v %v, want %v %v", tt.artist, track.Artist, track.Artists, track.FeaturedArtists, tt.artists, tt.featured)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/filestore"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotCached     = errors.New("no cached metadata for video")
+	ErrCacheDisabled = errors.New("meta cache is disabled")
+)
+
+// maxCachedCandidates bounds the candidates kept per entry, only the best few are ever shown for review
+const maxCachedCandidates = 10
+
+// CacheEntry is the resolved metadata of a video: the raw YouTube meta, the scored candidates and the chosen
+// match. A Reviewed entry holds a choice made through manual review, it does not expire.
+type CacheEntry struct {
+	ID          string       `json:"id"`
+	YoutubeMeta TrackMeta    `json:"youtube_meta"`
+	Candidates  []ScoredMeta `json:"candidates,omitempty"`
+	Meta        TrackMeta    `json:"meta"`
+	Matched     bool         `json:"matched"`
+	Reviewed    bool         `json:"reviewed,omitempty"`
+	CachedAt    time.Time    `json:"cached_at"`
+	ExpiresAt   time.Time    `json:"expires_at,omitempty"`
+}
+
+// Cache is the persistent record of resolved metadata keyed by YouTube ID, it saves retries and re-downloads the
+// music API request and the provider searches.
+type Cache struct {
+	mu      sync.RWMutex
+	path    string
+	ttl     time.Duration
+	Entries map[string]*CacheEntry `json:"entries"`
+}
+
+func NewCache(stateDir string, ttl time.Duration) (*Cache, error) {
+	c := &Cache{path: filepath.Join(stateDir, "meta_cache.json"), ttl: ttl, Entries: make(map[string]*CacheEntry)}
+	if err := filestore.Load(c.path, c); err != nil {
+		return nil, err
+	}
+	if c.Entries == nil {
+		c.Entries = make(map[string]*CacheEntry)
+	}
+	return c, nil
+}
+
+// Get returns a copy of the unexpired entry for the video.
+func (c *Cache) Get(id string) (*CacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.Entries[id]
+	if !ok || entry.expired(time.Now()) {
+		return nil, false
+	}
+	out := *entry
+	return &out, true
+}
+
+// Put caches a resolved result for the video. A reviewed choice already in the cache is kept.
+func (c *Cache) Put(id string, result *MetaResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.Entries[id]; ok && old.Reviewed {
+		return nil
+	}
+	now := time.Now()
+	c.Entries[id] = &CacheEntry{
+		ID:          id,
+		YoutubeMeta: result.YoutubeMeta,
+		Candidates:  result.Candidates[:min(len(result.Candidates), maxCachedCandidates)],
+		Meta:        *result.Meta,
+		Matched:     result.Matched,
+		CachedAt:    now,
+		ExpiresAt:   now.Add(c.ttl),
+	}
+	return c.save()
+}
+
+// SetReviewed records the metadata picked through manual review as the video's match.
+func (c *Cache) SetReviewed(id string, trackMeta TrackMeta) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.Entries[id]
+	if !ok {
+		entry = &CacheEntry{ID: id}
+		c.Entries[id] = entry
+	}
+	entry.Meta = trackMeta
+	entry.Matched = true
+	entry.Reviewed = true
+	entry.CachedAt = time.Now()
+	entry.ExpiresAt = time.Time{}
+	return c.save()
+}
+
+// List returns every unexpired entry, most recently cached first.
+func (c *Cache) List() []CacheEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	entries := make([]CacheEntry, 0, len(c.Entries))
+	for _, entry := range c.Entries {
+		if !entry.expired(now) {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CachedAt.After(entries[j].CachedAt) })
+	return entries
+}
+
+// Delete removes the video's entry, it reports false when there was none.
+func (c *Cache) Delete(id string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.Entries[id]; !ok {
+		return false, nil
+	}
+	delete(c.Entries, id)
+	return true, c.save()
+}
+
+// Clear removes every entry and returns how many there were.
+func (c *Cache) Clear() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	count := len(c.Entries)
+	c.Entries = make(map[string]*CacheEntry)
+	return count, c.save()
+}
+
+// save drops expired entries and persists the cache, the caller must hold c.mu.
+func (c *Cache) save() error {
+	now := time.Now()
+	for id, entry := range c.Entries {
+		if entry.expired(now) {
+			delete(c.Entries, id)
+		}
+	}
+	return filestore.Save(c.path, c)
+}
+
+func (e *CacheEntry) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
+
+// result rebuilds the MetaResult the entry was cached from. The meta is a copy, callers complete it in place.
+func (e *CacheEntry) result() *MetaResult {
+	trackMeta := e.Meta
+	return &MetaResult{
+		Meta:        &trackMeta,
+		Matched:     e.Matched,
+		YoutubeMeta: e.YoutubeMeta,
+		Candidates:  append(make([]ScoredMeta, 0, len(e.Candidates)), e.Candidates...),
+	}
+}
+
+func (s *Service) GetCachedMeta(ctx context.Context, id string) (*CacheEntry, error) {
+	if s.Cache == nil {
+		return nil, ErrCacheDisabled
+	}
+	entry, ok := s.Cache.Get(id)
+	if !ok {
+		return nil, ErrNotCached
+	}
+	return entry, nil
+}
+
+func (s *Service) ListCachedMeta(ctx context.Context) ([]CacheEntry, error) {
+	if s.Cache == nil {
+		return nil, ErrCacheDisabled
+	}
+	return s.Cache.List(), nil
+}
+
+// InvalidateCachedMeta drops the video's entry so its metadata is resolved again on the next download.
+func (s *Service) InvalidateCachedMeta(ctx context.Context, id string) error {
+	if s.Cache == nil {
+		return ErrCacheDisabled
+	}
+	removed, err := s.Cache.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotCached
+	}
+	zaplog.InfoC(ctx, "cached meta invalidated", zap.String("id", id))
+	return nil
+}
+
+func (s *Service) ClearCachedMeta(ctx context.Context) (int, error) {
+	if s.Cache == nil {
+		return 0, ErrCacheDisabled
+	}
+	count, err := s.Cache.Clear()
+	if err != nil {
+		return 0, err
+	}
+	zaplog.InfoC(ctx, "meta cache cleared", zap.Int("removed", count))
+	return count, nil
+}
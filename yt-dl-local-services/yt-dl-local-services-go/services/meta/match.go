@@ -0,0 +1,319 @@
+package meta
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/artists"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/qualifiers"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/textnorm"
+)
+
+// ScoredMeta is a provider candidate with the confidence that it is the track in the video, from 0 to 1.
+type ScoredMeta struct {
+	Meta        TrackMeta `json:"meta"`
+	Score       float64   `json:"score"`
+	TitleScore  float64   `json:"title_score"`
+	ArtistScore float64   `json:"artist_score"`
+	Penalties   []string  `json:"penalties,omitempty"`
+	// DurationMismatch is set when both durations are known and differ by more than the configured tolerance
+	DurationMismatch bool `json:"duration_mismatch,omitempty"`
+}
+
+const (
+	// artistWeight is the share of the title score that depends on the artist, so a right artist cannot make up
+	// for a wrong title but a right title with an unknown artist still keeps some confidence
+	artistWeight = 0.45
+	// qualifierPenalty is subtracted for every remix, live or cover mismatch between the video and a candidate, one
+	// mismatch keeps even a perfect title and artist below the default threshold of 0.8
+	qualifierPenalty = 0.25
+	// artistMatch is the similarity above which a credited artist counts as present in the video
+	artistMatch = 0.85
+	// durationWeight is the most a duration difference within the tolerance takes off the score
+	durationWeight = 0.1
+)
+
+var (
+	titleSeparators = regexp.MustCompile(`\s+[-–—~|:]+\s+|\s*[|~]\s*|:\s+`)
+	coverWords      = regexp.MustCompile(`(?i)\b(cover|covered|karaoke|tribute|originally performed)\b`)
+)
+
+// ScoreCandidates ranks provider candidates against the YouTube title, channel and duration, best first.
+func (s *Service) ScoreCandidates(trackMeta TrackMeta, coverArtist string, candidates []TrackMeta, tolerance time.Duration) []ScoredMeta {
+	transliterate := s.Config.MetadataTransliterate
+	videoTitles := withTransliterations(titleVariants(trackMeta.Title), transliterate)
+	artistVariants := []string{normalizeMatchText(s.SanitizeAuthor(trackMeta.Artist))}
+	if coverArtist != "" {
+		artistVariants = append(artistVariants, normalizeMatchText(coverArtist))
+	}
+	for _, part := range titleParts(trackMeta.Title) {
+		artistVariants = append(artistVariants, normalizeMatchText(part))
+	}
+	artistVariants = withTransliterations(artistVariants, transliterate)
+	haystacks := withTransliterations([]string{normalizeMatchText(trackMeta.Title + " " + trackMeta.Artist)}, transliterate)
+
+	scored := make([]ScoredMeta, 0, len(candidates))
+	for _, candidate := range candidates {
+		result := ScoredMeta{Meta: candidate}
+		for _, variant := range videoTitles {
+			for _, candidateTitle := range withTransliterations(titleVariants(candidate.Title), transliterate) {
+				result.TitleScore = max(result.TitleScore, similarity(variant, candidateTitle))
+			}
+		}
+		result.ArtistScore = artistSetScore(candidate.Artist, artistVariants, haystacks, transliterate)
+		result.Penalties = qualifierMismatches(trackMeta, coverArtist, candidate)
+		result.Score = result.TitleScore*(1-artistWeight+artistWeight*result.ArtistScore) - qualifierPenalty*float64(len(result.Penalties))
+		if trackMeta.DurationMs > 0 && candidate.DurationMs > 0 && tolerance > 0 {
+			diff := time.Duration(abs(trackMeta.DurationMs-candidate.DurationMs)) * time.Millisecond
+			result.DurationMismatch = diff > tolerance
+			result.Score -= durationWeight * min(float64(diff)/float64(tolerance), 1)
+		}
+		result.Score = max(result.Score, 0)
+		scored = append(scored, result)
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+	return scored
+}
+
+// titleParts splits a title on the separators uploaders put between artist, title and extras.
+func titleParts(title string) []string {
+	parts := make([]string, 0)
+	for _, part := range titleSeparators.Split(title, -1) {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// titleVariants returns every contiguous run of title parts, with and without the featured artists and the
+// bracketed extras, so "Artist - Title (Official Video) - Label" still yields a clean "title".
+func titleVariants(title string) []string {
+	parts := titleParts(title)
+	seen := make(map[string]bool)
+	variants := make([]string, 0)
+	add := func(str string) {
+		withoutFeatured, _ := artists.ParseTitle(str)
+		for _, v := range []string{str, withoutFeatured, bracketed.ReplaceAllString(str, "")} {
+			if v = normalizeMatchText(v); v != "" && !seen[v] {
+				seen[v] = true
+				variants = append(variants, v)
+			}
+		}
+	}
+	for i := range parts {
+		for j := i + 1; j <= len(parts); j++ {
+			add(strings.Join(parts[i:j], " "))
+		}
+	}
+	return variants
+}
+
+var bracketed = regexp.MustCompile(`\([^()]*\)|\[[^\[\]]*\]`)
+
+// artistSetScore combines the best similarity of any credited artist with the share of credited artists that
+// appear in the video title or channel, so a match on the main artist counts most but missing features cost.
+func artistSetScore(candidateArtist string, artistVariants []string, haystacks []string, transliterate bool) float64 {
+	names := artists.Parse(candidateArtist).All()
+	best := 0.0
+	matched := 0
+	total := 0
+	for _, name := range names {
+		name = normalizeMatchText(name)
+		if name == "" {
+			continue
+		}
+		total++
+		nameBest := 0.0
+		for _, form := range withTransliterations([]string{name}, transliterate) {
+			for _, haystack := range haystacks {
+				if strings.Contains(" "+haystack+" ", " "+form+" ") {
+					nameBest = 1
+				}
+			}
+			for _, variant := range artistVariants {
+				nameBest = max(nameBest, similarity(form, variant))
+			}
+		}
+		best = max(best, nameBest)
+		if nameBest >= artistMatch {
+			matched++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return 0.7*best + 0.3*float64(matched)/float64(total)
+}
+
+// qualifierMismatches lists the version markers (remix, live, acoustic, instrumental, speed) and the cover marker
+// that only one side of the match carries. The candidate's album counts too, a live album marks its tracks live.
+func qualifierMismatches(trackMeta TrackMeta, coverArtist string, candidate TrackMeta) []string {
+	candidateVersion := qualifiers.Parse(candidate.Title).Version
+	candidateVersion.Merge(qualifiers.Detect(candidate.Album))
+	penalties := qualifiers.Parse(trackMeta.Title).Version.Mismatches(candidateVersion)
+	candidateText := candidate.Title + " " + candidate.Album
+	isCover := coverArtist != "" || coverWords.MatchString(trackMeta.Title)
+	if coverWords.MatchString(candidateText+" "+candidate.Artist) && !isCover {
+		penalties = append(penalties, "cover")
+	} else if coverArtist != "" && similarity(normalizeMatchText(coverArtist), normalizeMatchText(candidate.Artist)) < artistMatch {
+		penalties = append(penalties, "cover")
+	}
+	return penalties
+}
+
+// withTransliterations adds the romanized form of every normalized string that is written in another script.
+func withTransliterations(forms []string, transliterate bool) []string {
+	if !transliterate {
+		return forms
+	}
+	out := append(make([]string, 0, len(forms)*2), forms...)
+	for _, form := range forms {
+		if latin := normalizeMatchText(textnorm.Transliterate(form)); latin != form && latin != "" {
+			out = append(out, latin)
+		}
+	}
+	return out
+}
+
+// normalizeMatchText folds a string with textnorm.Fold and reduces it to letters and digits separated by single
+// spaces. Letters of every script are kept, so a title in Japanese or Cyrillic still has something to compare.
+func normalizeMatchText(str string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range textnorm.Fold(str) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) {
+			if space && b.Len() > 0 {
+				b.WriteRune(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		} else if r != '\'' && r != '’' {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// similarity is the higher of the Jaro-Winkler similarity and the token set ratio of two normalized strings.
+// Jaro-Winkler catches typos and small spelling differences, the token set ratio catches reordered and extra words.
+func similarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	return max(jaroWinkler(a, b), tokenSetRatio(a, b))
+}
+
+func jaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	window := max(len(ra), len(rb))/2 - 1
+	window = max(window, 0)
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		for j := max(0, i-window); j < min(len(rb), i+window+1); j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// tokenSetRatio compares the word sets of two strings with the shared words sorted first, so word order does not
+// matter. Unlike fuzzywuzzy's token set ratio a subset is not a full match, "love" must not match "i will always
+// love you".
+func tokenSetRatio(a, b string) float64 {
+	tokensA := tokenSet(a)
+	tokensB := tokenSet(b)
+	inter := make([]string, 0)
+	onlyA := make([]string, 0)
+	onlyB := make([]string, 0)
+	for token := range tokensA {
+		if tokensB[token] {
+			inter = append(inter, token)
+		} else {
+			onlyA = append(onlyA, token)
+		}
+	}
+	for token := range tokensB {
+		if !tokensA[token] {
+			onlyB = append(onlyB, token)
+		}
+	}
+	sort.Strings(inter)
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	shared := strings.Join(inter, " ")
+	combinedA := strings.TrimSpace(shared + " " + strings.Join(onlyA, " "))
+	combinedB := strings.TrimSpace(shared + " " + strings.Join(onlyB, " "))
+	return indelRatio(combinedA, combinedB)
+}
+
+func tokenSet(str string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, token := range strings.Fields(str) {
+		tokens[token] = true
+	}
+	return tokens
+}
+
+// indelRatio is 2*LCS/(len(a)+len(b)), the similarity measure behind fuzzywuzzy's ratio.
+func indelRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra)+len(rb) == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			if ra[i-1] == rb[j-1] {
+				cur[j] = prev[j-1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j-1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return 2 * float64(prev[len(rb)]) / float64(len(ra)+len(rb))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
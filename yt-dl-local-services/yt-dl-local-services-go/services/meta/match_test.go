@@ -0,0 +1,127 @@
+package meta
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+)
+
+func TestScoreCandidates(t *testing.T) {
+	const threshold = 0.8
+	tests := []struct {
+		name      string
+		video     TrackMeta
+		candidate TrackMeta
+		accepted  bool
+		penalties []string
+		mismatch  bool
+	}{
+		{"exact",
+			TrackMeta{Title: "Rick Astley - Never Gonna Give You Up (Official Music Video)", Artist: "Rick Astley", DurationMs: 213000},
+			TrackMeta{Title: "Never Gonna Give You Up", Artist: "Rick Astley", DurationMs: 213000}, true, nil, false},
+		{"typo",
+			TrackMeta{Title: "Rick Astley - Never Gona Give You Up", Artist: "Rick Astley"},
+			TrackMeta{Title: "Never Gonna Give You Up", Artist: "Rick Astley"}, true, nil, false},
+		{"other song by the artist",
+			TrackMeta{Title: "Rick Astley - Never Gonna Give You Up", Artist: "Rick Astley"},
+			TrackMeta{Title: "Together Forever", Artist: "Rick Astley"}, false, nil, false},
+		{"subset of the title",
+			TrackMeta{Title: "Whitney Houston - I Will Always Love You", Artist: "Whitney Houston"},
+			TrackMeta{Title: "Love", Artist: "Whitney Houston"}, false, nil, false},
+		{"feat. in the video title and credited by the provider",
+			TrackMeta{Title: "Calvin Harris - This Is What You Came For (Official Video) ft. Rihanna", Artist: "CalvinHarrisVEVO"},
+			TrackMeta{Title: "This Is What You Came For (feat. Rihanna)", Artist: "Calvin Harris, Rihanna"}, true, nil, false},
+		{"remix video and original candidate",
+			TrackMeta{Title: "Calvin Harris - Summer (R3hab & Ummet Ozcan Remix)", Artist: "Calvin Harris"},
+			TrackMeta{Title: "Summer", Artist: "Calvin Harris"}, false, []string{"remix"}, false},
+		{"original video and remix candidate",
+			TrackMeta{Title: "Calvin Harris - Summer", Artist: "Calvin Harris"},
+			TrackMeta{Title: "Summer - R3hab Remix", Artist: "Calvin Harris"}, false, []string{"remix"}, false},
+		{"remix video and remix candidate",
+			TrackMeta{Title: "Calvin Harris - Summer (R3hab & Ummet Ozcan Remix)", Artist: "Calvin Harris"},
+			TrackMeta{Title: "Summer - R3hab & Ummet Ozcan Remix", Artist: "Calvin Harris"}, true, nil, false},
+		{"live video and studio candidate",
+			TrackMeta{Title: "Oasis - Wonderwall (Live at Knebworth)", Artist: "Oasis"},
+			TrackMeta{Title: "Wonderwall", Artist: "Oasis", Album: "(What's The Story) Morning Glory?"}, false, []string{"live"}, false},
+		{"live video and track of a live album",
+			TrackMeta{Title: "Oasis - Wonderwall (Live at Knebworth)", Artist: "Oasis"},
+			TrackMeta{Title: "Wonderwall", Artist: "Oasis", Album: "Knebworth 1996 (Live)"}, true, nil, false},
+		{"studio video and track of a live album",
+			TrackMeta{Title: "Oasis - Wonderwall", Artist: "Oasis"},
+			TrackMeta{Title: "Wonderwall", Artist: "Oasis", Album: "Knebworth 1996 (Live)"}, false, []string{"live"}, false},
+		{"accents",
+			TrackMeta{Title: "Beyonce - Halo", Artist: "Beyonce"},
+			TrackMeta{Title: "Halo", Artist: "Beyoncé"}, true, nil, false},
+		{"cyrillic",
+			TrackMeta{Title: "Кино - Группа крови", Artist: "Кино"},
+			TrackMeta{Title: "Группа крови", Artist: "Кино"}, true, nil, false},
+		{"romanized video of a cyrillic track",
+			TrackMeta{Title: "Kino - Gruppa krovi", Artist: "Kino"},
+			TrackMeta{Title: "Группа крови", Artist: "Кино"}, true, nil, false},
+		{"full-width japanese",
+			TrackMeta{Title: "ＹＯＡＳＯＢＩ - 夜に駆ける", Artist: "Ayase / YOASOBI"},
+			TrackMeta{Title: "夜に駆ける", Artist: "YOASOBI"}, true, nil, false},
+		{"unknown artist",
+			TrackMeta{Title: "Never Gonna Give You Up", Artist: "Some Channel"},
+			TrackMeta{Title: "Never Gonna Give You Up", Artist: "Rick Astley"}, false, nil, false},
+		{"duration within the tolerance",
+			TrackMeta{Title: "Rick Astley - Never Gonna Give You Up", Artist: "Rick Astley", DurationMs: 213000},
+			TrackMeta{Title: "Never Gonna Give You Up", Artist: "Rick Astley", DurationMs: 228000}, true, nil, false},
+		{"duration outside the tolerance",
+			TrackMeta{Title: "Rick Astley - Never Gonna Give You Up", Artist: "Rick Astley", DurationMs: 213000},
+			TrackMeta{Title: "Never Gonna Give You Up (Extended Mix)", Artist: "Rick Astley", DurationMs: 300000}, false, nil, true},
+	}
+	s := &Service{Config: &config.Config{MetadataTransliterate: true}}
+	for _, tt := range tests {
+		got := s.ScoreCandidates(tt.video, "", []TrackMeta{tt.candidate}, 15*time.Second)[0]
+		if accepted := got.Score >= threshold && !got.DurationMismatch; accepted != tt.accepted {
+			t.Errorf("%s: score %.3f (title %.2f, artist %.2f), accepted %v, want %v", tt.name, got.Score, got.TitleScore, got.ArtistScore, accepted, tt.accepted)
+		}
+		if len(got.Penalties) > 0 || len(tt.penalties) > 0 {
+			if !reflect.DeepEqual(got.Penalties, tt.penalties) {
+				t.Errorf("%s: penalties %v, want %v", tt.name, got.Penalties, tt.penalties)
+			}
+		}
+		if got.DurationMismatch != tt.mismatch {
+			t.Errorf("%s: duration mismatch %v, want %v", tt.name, got.DurationMismatch, tt.mismatch)
+		}
+	}
+}
+
+func TestScoreCandidatesRanksMatchingVersionFirst(t *testing.T) {
+	s := &Service{Config: &config.Config{}}
+	video := TrackMeta{Title: "Calvin Harris - Summer (R3hab & Ummet Ozcan Remix)", Artist: "Calvin Harris"}
+	candidates := []TrackMeta{
+		{Title: "Summer", Artist: "Calvin Harris"},
+		{Title: "Summer - Diplo Remix", Artist: "Calvin Harris"},
+		{Title: "Summer - R3hab & Ummet Ozcan Remix", Artist: "Calvin Harris"},
+	}
+	scored := s.ScoreCandidates(video, "", candidates, 0)
+	if scored[0].Meta.Title != candidates[2].Title {
+		t.Errorf("best candidate %q, want %q", scored[0].Meta.Title, candidates[2].Title)
+	}
+	if scored[len(scored)-1].Meta.Title == candidates[2].Title {
+		t.Errorf("matching remix ranked last")
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"never gonna give you up", "never gonna give you up", 1, 1},
+		{"give you up never gonna", "never gonna give you up", 1, 1},
+		{"never gona give you up", "never gonna give you up", 0.95, 1},
+		{"love", "i will always love you", 0, 0.6},
+		{"", "song", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := similarity(tt.a, tt.b); got < tt.min || got > tt.max {
+			t.Errorf("similarity(%q, %q) = %.3f, want between %.2f and %.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
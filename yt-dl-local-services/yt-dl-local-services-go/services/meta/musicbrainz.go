@@ -0,0 +1,217 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/artists"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/http_client"
+	"go.uber.org/zap"
+)
+
+// musicBrainzInterval is the minimum time between two requests, MusicBrainz blocks clients going above 1 req/s.
+const musicBrainzInterval = time.Second
+
+// MusicBrainzProvider searches MusicBrainz recordings, it needs no credentials. Cover art comes from the
+// Cover Art Archive.
+type MusicBrainzProvider struct {
+	Config     *config.Config
+	HTTPClient *http_client.HTTPClient
+
+	mu          sync.Mutex
+	lastRequest time.Time
+}
+
+func NewMusicBrainzProvider(cfg *config.Config, httpClient *http_client.HTTPClient) *MusicBrainzProvider {
+	return &MusicBrainzProvider{Config: cfg, HTTPClient: httpClient}
+}
+
+type MusicBrainzArtistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+	Artist     struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+}
+
+type MusicBrainzRelease struct {
+	ID           string                    `json:"id"`
+	Title        string                    `json:"title"`
+	Status       string                    `json:"status"`
+	Date         string                    `json:"date"`
+	ArtistCredit []MusicBrainzArtistCredit `json:"artist-credit"`
+	ReleaseGroup struct {
+		ID          string `json:"id"`
+		PrimaryType string `json:"primary-type"`
+	} `json:"release-group"`
+	Media []struct {
+		Position   int `json:"position"`
+		TrackCount int `json:"track-count"`
+		Track      []struct {
+			Number string `json:"number"`
+		} `json:"track"`
+	} `json:"media"`
+}
+
+type MusicBrainzRecording struct {
+	ID               string                    `json:"id"`
+	Score            int                       `json:"score"`
+	Title            string                    `json:"title"`
+	Length           int                       `json:"length"`
+	FirstReleaseDate string                    `json:"first-release-date"`
+	ArtistCredit     []MusicBrainzArtistCredit `json:"artist-credit"`
+	ISRCs            []string                  `json:"isrcs"`
+	Releases         []MusicBrainzRelease      `json:"releases"`
+}
+
+func (p *MusicBrainzProvider) Name() string {
+	return ProviderMusicBrainz
+}
+
+func (p *MusicBrainzProvider) Search(ctx context.Context, trackMeta TrackMeta) ([]TrackMeta, error) {
+	searchTerm := fmt.Sprintf(`recording:"%s" AND artist:"%s"`, escapeLucene(trackMeta.Title), escapeLucene(trackMeta.Artist))
+	zaplog.InfoC(ctx, "searching musicbrainz", zap.String("searchTerm", searchTerm))
+	query := url.Values{}
+	query.Set("query", searchTerm)
+	query.Set("fmt", "json")
+	query.Set("limit", "10")
+	req, err := p.HTTPClient.CreateRequest(http.MethodGet, fmt.Sprintf("%s/ws/2/recording?%s", strings.TrimRight(p.Config.MusicBrainzURL, "/"), query.Encode()), nil)
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to create musicbrainz request", zap.Error(err))
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "yt-dl-3-hybrid (https://github.com/gcottom/yt-dl-3-hybrid)")
+	req.Header.Set("Accept", "application/json")
+	p.wait()
+	res, status, err := p.HTTPClient.DoRequest(req)
+	if err != nil {
+		zaplog.ErrorC(ctx, "error while sending musicbrainz request", zap.Error(err))
+		return nil, err
+	}
+	if status != http.StatusOK {
+		zaplog.ErrorC(ctx, "musicbrainz request failed", zap.Int("code", status))
+		return nil, fmt.Errorf("musicbrainz request failed: %d", status)
+	}
+	var data struct {
+		Recordings []MusicBrainzRecording `json:"recordings"`
+	}
+	if err := json.Unmarshal(res, &data); err != nil {
+		zaplog.ErrorC(ctx, "failed to unmarshal musicbrainz response", zap.Error(err))
+		return nil, err
+	}
+	trackMetas := make([]TrackMeta, 0)
+	for _, recording := range data.Recordings {
+		trackMetas = append(trackMetas, p.recordingMeta(recording))
+	}
+	zaplog.InfoC(ctx, "musicbrainz search results", zap.Any("results", trackMetas))
+	return trackMetas, nil
+}
+
+// wait blocks until the rate limit allows the next request. Requests from concurrent downloads queue up here.
+func (p *MusicBrainzProvider) wait() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if d := musicBrainzInterval - time.Since(p.lastRequest); d > 0 {
+		time.Sleep(d)
+	}
+	p.lastRequest = time.Now()
+}
+
+func (p *MusicBrainzProvider) recordingMeta(recording MusicBrainzRecording) TrackMeta {
+	resMeta := TrackMeta{
+		Title:                  recording.Title,
+		Artist:                 joinArtistCredit(recording.ArtistCredit),
+		DurationMs:             recording.Length,
+		ReleaseDate:            recording.FirstReleaseDate,
+		MusicBrainzRecordingID: recording.ID,
+	}
+	credit := creditFromMusicBrainz(recording.ArtistCredit)
+	resMeta.Artists = credit.All()
+	resMeta.FeaturedArtists = credit.Featured
+	for _, credit := range recording.ArtistCredit {
+		resMeta.MusicBrainzArtistIDs = append(resMeta.MusicBrainzArtistIDs, credit.Artist.ID)
+	}
+	if len(recording.ISRCs) > 0 {
+		resMeta.ISRC = recording.ISRCs[0]
+	}
+	release := preferredRelease(recording.Releases)
+	if release != nil {
+		resMeta.Album = release.Title
+		resMeta.AlbumArtist = joinArtistCredit(release.ArtistCredit)
+		resMeta.MusicBrainzReleaseID = release.ID
+		resMeta.MusicBrainzReleaseGroupID = release.ReleaseGroup.ID
+		if release.Date != "" {
+			resMeta.ReleaseDate = release.Date
+		}
+		if len(release.Media) > 0 {
+			resMeta.DiscNumber = release.Media[0].Position
+			resMeta.TrackTotal = release.Media[0].TrackCount
+			if len(release.Media[0].Track) > 0 {
+				resMeta.TrackNumber, _ = strconv.Atoi(release.Media[0].Track[0].Number)
+			}
+		}
+		resMeta.CoverArtURL = fmt.Sprintf("%s/release/%s/front-500", strings.TrimRight(p.Config.CoverArtArchiveURL, "/"), release.ID)
+	}
+	resMeta.Year = releaseYear(resMeta.ReleaseDate)
+	return resMeta
+}
+
+// preferredRelease picks the release to tag a recording with, official albums first, then any official release.
+func preferredRelease(releases []MusicBrainzRelease) *MusicBrainzRelease {
+	var official *MusicBrainzRelease
+	for i, release := range releases {
+		if release.Status != "Official" {
+			continue
+		}
+		if release.ReleaseGroup.PrimaryType == "Album" {
+			return &releases[i]
+		}
+		if official == nil {
+			official = &releases[i]
+		}
+	}
+	if official == nil && len(releases) > 0 {
+		return &releases[0]
+	}
+	return official
+}
+
+func joinArtistCredit(credits []MusicBrainzArtistCredit) string {
+	var b strings.Builder
+	for _, credit := range credits {
+		b.WriteString(credit.Name)
+		b.WriteString(credit.JoinPhrase)
+	}
+	return b.String()
+}
+
+// creditFromMusicBrainz splits an artist credit into primary and featured artists, every artist after a join
+// phrase such as " feat. " or " with " is featured.
+func creditFromMusicBrainz(credits []MusicBrainzArtistCredit) artists.Credit {
+	out := artists.Credit{}
+	featured := false
+	for _, credit := range credits {
+		if featured {
+			out.AddFeatured(credit.Name)
+		} else {
+			out.Primary = append(out.Primary, credit.Name)
+		}
+		featured = featured || artists.IsFeatureJoin(credit.JoinPhrase)
+	}
+	return out
+}
+
+// escapeLucene escapes the characters that would end a quoted term in a MusicBrainz search query.
+func escapeLucene(str string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(str)
+}
@@ -0,0 +1,18 @@
+package meta
+
+import (
+	"context"
+)
+
+// SpotifyProvider searches the Spotify catalog, it needs the client credentials from the config.
+type SpotifyProvider struct {
+	Service *Service
+}
+
+func (p *SpotifyProvider) Name() string {
+	return ProviderSpotify
+}
+
+func (p *SpotifyProvider) Search(ctx context.Context, trackMeta TrackMeta) ([]TrackMeta, error) {
+	return p.Service.GetSpotifyMeta(ctx, trackMeta)
+}
@@ -3,34 +3,181 @@ package meta
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gcottom/go-zaplog"
 	"github.com/gcottom/retry"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/coverart"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/qualifiers"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/textnorm"
 	"github.com/zmb3/spotify/v2"
-	spotifyauth "github.com/zmb3/spotify/v2/auth"
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
 )
 
-func (s *Service) GetBestMeta(ctx context.Context, id string) (*TrackMeta, error) {
-	res, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s.GetYTMetaFromID, ctx, id)
+// GetBestMeta resolves a video's metadata with the configured providers in order. The first provider with a
+// match wins, when none has one the cleaned up YouTube title and channel are used and the candidates that came
+// closest are returned for review.
+func (s *Service) GetBestMeta(ctx context.Context, id string, overrides *Overrides) (*MetaResult, error) {
+	var trackMeta TrackMeta
+	entry, cached := s.cachedEntry(id)
+	if cached {
+		zaplog.InfoC(ctx, "using cached meta", zap.String("id", id), zap.Bool("matched", entry.Matched), zap.Bool("reviewed", entry.Reviewed))
+		trackMeta = entry.YoutubeMeta
+	} else {
+		res, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, s.GetYTMetaFromID, ctx, id)
+		if err != nil {
+			zaplog.ErrorC(ctx, "failed to get yt meta", zap.Error(err))
+			return nil, err
+		}
+		trackMeta = res[0].(TrackMeta)
+	}
+	if overrides.IdentifiesTrack() {
+		zaplog.InfoC(ctx, "meta given with the request, skipping provider matching", zap.String("title", overrides.Title), zap.String("artist", overrides.Artist))
+		bestMeta := s.FallbackMeta(trackMeta)
+		bestMeta.Album = ""
+		overrides.Apply(&bestMeta)
+		if bestMeta.Album == "" {
+			bestMeta.Album = bestMeta.Title
+		}
+		bestMeta.MatchNote = MatchNoteOverridden
+		return &MetaResult{Meta: &bestMeta, Matched: true, YoutubeMeta: trackMeta, Candidates: make([]ScoredMeta, 0)}, nil
+	}
+	if cached {
+		return entry.result(), nil
+	}
+	result, err := s.ResolveMeta(ctx, trackMeta)
 	if err != nil {
-		zaplog.ErrorC(ctx, "failed to get yt meta", zap.Error(err))
 		return nil, err
 	}
-	trackMeta := res[0].(TrackMeta)
-	res, err = retry.Retry(retry.NewAlgSimpleDefault(), 3, s.GetSpotifyMeta, ctx, trackMeta)
+	if s.Cache != nil && !result.Incomplete {
+		if err := s.Cache.Put(id, result); err != nil {
+			zaplog.ErrorC(ctx, "failed to cache meta", zap.String("id", id), zap.Error(err))
+		}
+	}
+	return result, nil
+}
+
+// cachedEntry returns the video's cache entry when the cache is enabled and has one.
+func (s *Service) cachedEntry(id string) (*CacheEntry, bool) {
+	if s.Cache == nil {
+		return nil, false
+	}
+	return s.Cache.Get(id)
+}
+
+// ResolveMeta matches known metadata, from a video or from the existing tags of a file, against the providers.
+func (s *Service) ResolveMeta(ctx context.Context, trackMeta TrackMeta) (*MetaResult, error) {
+	result := &MetaResult{YoutubeMeta: trackMeta, Candidates: make([]ScoredMeta, 0)}
+	var lastErr error
+	searched := false
+	notes := make([]string, 0)
+	for _, provider := range s.Providers {
+		res, err := retry.Retry(retry.NewAlgSimpleDefault(), 3, provider.Search, ctx, trackMeta)
+		if err != nil {
+			zaplog.ErrorC(ctx, "failed to get provider meta", zap.String("provider", provider.Name()), zap.Error(err))
+			lastErr = err
+			continue
+		}
+		searched = true
+		bestMeta, scored, ok := s.GetBestMetaMatch(ctx, provider, trackMeta, res[0].([]TrackMeta))
+		if !ok {
+			zaplog.InfoC(ctx, "no meta match from provider", zap.String("provider", provider.Name()), zap.String("reason", bestMeta.MatchNote))
+			notes = append(notes, fmt.Sprintf("%s: %s", provider.Name(), bestMeta.MatchNote))
+			result.Candidates = append(result.Candidates, scored...)
+			continue
+		}
+		zaplog.InfoC(ctx, "meta matched", zap.String("provider", provider.Name()), zap.String("title", bestMeta.Title), zap.String("artist", bestMeta.Artist))
+		bestMeta.Version = versionOf(qualifiers.Parse(bestMeta.Title))
+		if bestMeta.CoverArtURL == "" || !s.CoverArtExists(ctx, bestMeta.CoverArtURL) {
+			bestMeta.CoverArtURL = trackMeta.CoverArtURL
+		}
+		result.Meta = &bestMeta
+		result.Matched = true
+		return result, nil
+	}
+	if !searched && lastErr != nil {
+		return nil, lastErr
+	}
+	bestMeta := s.FallbackMeta(trackMeta)
+	bestMeta.MatchNote = strings.Join(notes, "; ")
+	result.Meta = &bestMeta
+	result.Incomplete = lastErr != nil
+	sort.SliceStable(result.Candidates, func(i, j int) bool { return result.Candidates[i].Score > result.Candidates[j].Score })
+	return result, nil
+}
+
+// FallbackMeta is the metadata used when no provider matches the video. The version and featured artist qualifiers
+// of the title are kept, everything else in brackets is dropped.
+func (s *Service) FallbackMeta(trackMeta TrackMeta) TrackMeta {
+	title := qualifiers.Parse(trackMeta.Title)
+	sanitizedTitle := s.SanitizeString(title.Base)
+	for _, qualifier := range title.Qualifiers {
+		if qualifier = s.SanitizeString(qualifier); qualifier != "" {
+			sanitizedTitle += " (" + qualifier + ")"
+		}
+	}
+	return TrackMeta{Title: sanitizedTitle, Artist: trackMeta.Artist, Album: sanitizedTitle, CoverArtURL: trackMeta.CoverArtURL,
+		DurationMs: trackMeta.DurationMs, Version: versionOf(title)}
+}
+
+// versionOf returns the version of a parsed title, nil for the original recording.
+func versionOf(title qualifiers.Title) *qualifiers.Version {
+	if title.Version.IsZero() {
+		return nil
+	}
+	return &title.Version
+}
+
+// searchTitle is the title providers are searched with: the base title, and the remix qualifier so the remix
+// is among the results.
+func (s *Service) searchTitle(title string) string {
+	parsed := qualifiers.Parse(title)
+	searchTitle := s.SanitizeString(parsed.Base)
+	for _, qualifier := range parsed.Qualifiers {
+		if qualifiers.Detect(qualifier).Remix {
+			searchTitle += " " + s.SanitizeString(qualifier)
+		}
+	}
+	return searchTitle
+}
+
+// CoverArtExists checks a cover URL before it is handed to the Lambda, Cover Art Archive has no art for many
+// releases and a missing cover would fail the tagging step.
+func (s *Service) CoverArtExists(ctx context.Context, url string) bool {
+	req, err := s.HTTPClient.CreateRequest(http.MethodHead, url, nil)
 	if err != nil {
-		zaplog.ErrorC(ctx, "failed to get spotify meta", zap.Error(err))
-		return nil, err
+		return false
 	}
-	spotifyMetas := res[0].([]TrackMeta)
-	bestMeta := s.GetBestMetaMatch(ctx, trackMeta, spotifyMetas)
-	return &bestMeta, nil
+	resp, err := s.HTTPClient.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to check cover art", zap.String("url", url), zap.Error(err))
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// LargestCover returns the largest variant of a YouTube thumbnail or artwork URL that exists, the thumbnail the
+// music API reports is often a small one.
+func (s *Service) LargestCover(ctx context.Context, url string) string {
+	if url == "" {
+		return url
+	}
+	variants := coverart.Variants(url, s.Config.CoverArtMaxSize)
+	for _, variant := range variants[:len(variants)-1] {
+		if s.CoverArtExists(ctx, variant) {
+			return variant
+		}
+	}
+	return url
 }
 
 func (s *Service) GetYTMetaFromID(ctx context.Context, id string) (TrackMeta, error) {
@@ -49,7 +196,7 @@ func (s *Service) GetYTMetaFromID(ctx context.Context, id string) (TrackMeta, er
 		zaplog.ErrorC(ctx, "failed to unmarshal meta response", zap.Error(err))
 		return TrackMeta{}, err
 	}
-	outmeta := TrackMeta{Artist: meta.Author, Title: meta.Title, CoverArtURL: meta.Image}
+	outmeta := TrackMeta{Artist: meta.Author, Title: meta.Title, CoverArtURL: s.LargestCover(ctx, meta.Image), DurationMs: meta.Duration * 1000}
 	return outmeta, nil
 }
 
@@ -57,16 +204,10 @@ func (s *Service) GetSpotifyMeta(ctx context.Context, trackMeta TrackMeta) ([]Tr
 	searchTerm := fmt.Sprintf("track:%s artist:%s", trackMeta.Title, trackMeta.Artist)
 	zaplog.InfoC(ctx, "searching spotify", zap.String("searchTerm", searchTerm))
 
-	token, err := s.GetSpotifyToken(ctx)
-	if err != nil {
-		zaplog.ErrorC(ctx, "failed to get spotify token", zap.Error(err))
-		return nil, err
+	if s.spotifyClient == nil {
+		return nil, errors.New("spotify is not configured")
 	}
-
-	authClient := spotifyauth.New().Client(ctx, token)
-	spotifyClient := spotify.New(authClient)
-
-	res, err := spotifyClient.Search(ctx, searchTerm, spotify.SearchTypeTrack)
+	res, err := s.spotifyClient.Search(ctx, searchTerm, spotify.SearchTypeTrack)
 	if err != nil {
 		zaplog.ErrorC(ctx, "failed to search spotify", zap.Error(err))
 		return nil, err
@@ -84,9 +225,29 @@ func (s *Service) GetSpotifyMeta(ctx context.Context, trackMeta TrackMeta) ([]Tr
 			artists = append(artists, artist.Name)
 		}
 
+		albumArtists := make([]string, 0)
+		for _, artist := range track.Album.Artists {
+			albumArtists = append(albumArtists, artist.Name)
+		}
+
 		resMeta.Artist = strings.Join(artists, ", ")
+		resMeta.Artists = artists
+		resMeta.FeaturedArtists = spotifyFeatured(track.Name, artists)
 		resMeta.Album = track.Album.Name
 		resMeta.Title = track.Name
+		resMeta.AlbumArtist = strings.Join(albumArtists, ", ")
+		resMeta.TrackNumber = int(track.TrackNumber)
+		resMeta.DiscNumber = int(track.DiscNumber)
+		resMeta.ReleaseDate = track.Album.ReleaseDate
+		resMeta.Year = releaseYear(track.Album.ReleaseDate)
+		resMeta.ISRC = track.ExternalIDs["isrc"]
+		resMeta.Explicit = track.Explicit
+		resMeta.DurationMs = int(track.Duration)
+		resMeta.SpotifyTrackID = track.ID.String()
+		resMeta.SpotifyAlbumID = track.Album.ID.String()
+		for _, artist := range track.Artists {
+			resMeta.SpotifyArtistIDs = append(resMeta.SpotifyArtistIDs, artist.ID.String())
+		}
 		trackMetas = append(trackMetas, resMeta)
 	}
 
@@ -94,111 +255,99 @@ func (s *Service) GetSpotifyMeta(ctx context.Context, trackMeta TrackMeta) ([]Tr
 	return trackMetas, nil
 }
 
-func (s *Service) GetSpotifyToken(ctx context.Context) (*oauth2.Token, error) {
-	token, err := s.SpotifyConfig.Token(ctx)
+// releaseYear returns the year of a release date given as "2006", "2006-01" or "2006-01-02".
+func releaseYear(releaseDate string) int {
+	if len(releaseDate) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(releaseDate[:4])
 	if err != nil {
-		zaplog.ErrorC(ctx, "failed to get spotify token", zap.Error(err))
-		return nil, err
+		return 0
+	}
+	return year
+}
+
+// GetSpotifyToken returns the cached client credentials token, it is only refreshed once it expires.
+func (s *Service) GetSpotifyToken(ctx context.Context) (*oauth2.Token, error) {
+	if s.spotifyLimiter == nil {
+		return nil, errors.New("spotify is not configured")
 	}
-	return token, nil
+	return s.spotifyLimiter.Token()
 }
 
-func (s *Service) GetBestMetaMatch(ctx context.Context, trackMeta TrackMeta, spotifyMetas []TrackMeta) TrackMeta {
+// GetBestMetaMatch scores every provider result against the YouTube title and channel and returns the best one
+// when its score reaches the configured threshold. The second return value is false when nothing matched.
+func (s *Service) GetBestMetaMatch(ctx context.Context, provider MetadataProvider, trackMeta TrackMeta, candidates []TrackMeta) (TrackMeta, []ScoredMeta, bool) {
 	coverArtist := s.CoverArtistCheck(ctx, trackMeta.Title)
 	if coverArtist != "" {
 		zaplog.InfoC(ctx, "cover artist found", zap.String("coverArtist", coverArtist))
 	}
-	sanitizedTitle := s.SanitizeString(s.SanitizeParenthesis(trackMeta.Title))
+	sanitizedTitle := s.searchTitle(trackMeta.Title)
 	zaplog.InfoC(ctx, "sanitized title", zap.String("title", sanitizedTitle))
-	featStrippedTitle := strings.Split(sanitizedTitle, "feat")[0]
-	zaplog.InfoC(ctx, "feat stripped title", zap.String("title", featStrippedTitle))
-	titles := []string{trackMeta.Title, sanitizedTitle, featStrippedTitle}
-	artists := []string{trackMeta.Artist}
-	if coverArtist != "" {
-		artists = append(artists, s.SanitizeAuthor(coverArtist))
-	}
-	if len(spotifyMetas) == 0 {
-		spotifyMetas, err := s.GetSpotifyMeta(ctx, TrackMeta{Title: sanitizedTitle, Artist: trackMeta.Artist})
+	if len(candidates) == 0 {
+		var err error
+		candidates, err = provider.Search(ctx, TrackMeta{Title: sanitizedTitle, Artist: trackMeta.Artist})
 		if err != nil {
-			zaplog.ErrorC(ctx, "failed to get spotify meta", zap.Error(err))
-			return TrackMeta{Title: sanitizedTitle, Artist: trackMeta.Artist, Album: sanitizedTitle, CoverArtURL: trackMeta.CoverArtURL}
+			zaplog.ErrorC(ctx, "failed to get provider meta", zap.String("provider", provider.Name()), zap.Error(err))
+			return s.noMatch(trackMeta, fmt.Sprintf("search failed: %v", err)), nil, false
 		}
 		if coverArtist != "" {
-			caSpotifyMetas, err := s.GetSpotifyMeta(ctx, TrackMeta{Title: sanitizedTitle, Artist: coverArtist})
+			caCandidates, err := provider.Search(ctx, TrackMeta{Title: sanitizedTitle, Artist: coverArtist})
 			if err != nil {
-				zaplog.ErrorC(ctx, "failed to get spotify meta", zap.Error(err))
-				return TrackMeta{Title: sanitizedTitle, Artist: trackMeta.Artist, Album: sanitizedTitle, CoverArtURL: trackMeta.CoverArtURL}
-			}
-			spotifyMetas = append(spotifyMetas, caSpotifyMetas...)
-		}
-		if len(spotifyMetas) == 0 {
-			return TrackMeta{Title: sanitizedTitle, Artist: trackMeta.Artist, Album: sanitizedTitle, CoverArtURL: trackMeta.CoverArtURL}
-		}
-	}
-	sanitizedSplits := strings.Split(strings.ReplaceAll(sanitizedTitle, ":", "-"), "-")
-	if len(sanitizedSplits) < 2 {
-		titles = append(titles, sanitizedSplits[0])
-	}
-	if len(sanitizedSplits) == 2 {
-		titles = append(titles, sanitizedSplits[0], sanitizedSplits[1])
-		artists = append(artists, s.SanitizeAuthor(sanitizedSplits[0]), s.SanitizeAuthor(sanitizedSplits[1]))
-	} else if len(sanitizedSplits) == 3 {
-		titles = append(titles, sanitizedSplits[0], sanitizedSplits[1], sanitizedSplits[2], fmt.Sprintf("%s %s", sanitizedSplits[0], sanitizedSplits[1]), fmt.Sprintf("%s %s", sanitizedSplits[1], sanitizedSplits[2]))
-		artists = append(artists, s.SanitizeAuthor(sanitizedSplits[0]), s.SanitizeAuthor(sanitizedSplits[1]), s.SanitizeAuthor(sanitizedSplits[2]), s.SanitizeAuthor(fmt.Sprintf("%s %s", sanitizedSplits[0], sanitizedSplits[1])), s.SanitizeAuthor(fmt.Sprintf("%s %s", sanitizedSplits[1], sanitizedSplits[2])))
-	} else if len(sanitizedSplits) == 4 {
-		titles = append(titles, sanitizedSplits[0], sanitizedSplits[1], sanitizedSplits[2], sanitizedSplits[3], fmt.Sprintf("%s %s", sanitizedSplits[0], sanitizedSplits[1]), fmt.Sprintf("%s %s", sanitizedSplits[1], sanitizedSplits[2]), fmt.Sprintf("%s %s", sanitizedSplits[2], sanitizedSplits[3]), fmt.Sprintf("%s %s %s", sanitizedSplits[0], sanitizedSplits[1], sanitizedSplits[2]), fmt.Sprintf("%s %s %s", sanitizedSplits[1], sanitizedSplits[2], sanitizedSplits[3]), fmt.Sprintf("%s %s", sanitizedSplits[0], sanitizedSplits[1]))
-		artists = append(artists, s.SanitizeAuthor(sanitizedSplits[0]), s.SanitizeAuthor(sanitizedSplits[1]), s.SanitizeAuthor(sanitizedSplits[2]), s.SanitizeAuthor(sanitizedSplits[3]), s.SanitizeAuthor(fmt.Sprintf("%s %s", sanitizedSplits[0], sanitizedSplits[1])), s.SanitizeAuthor(fmt.Sprintf("%s %s", sanitizedSplits[1], sanitizedSplits[2])), s.SanitizeAuthor(fmt.Sprintf("%s %s", sanitizedSplits[2], sanitizedSplits[3])), s.SanitizeAuthor(fmt.Sprintf("%s %s %s", sanitizedSplits[0], sanitizedSplits[1], sanitizedSplits[2])), s.SanitizeAuthor(fmt.Sprintf("%s %s %s", sanitizedSplits[1], sanitizedSplits[2], sanitizedSplits[3])), s.SanitizeAuthor(fmt.Sprintf("%s %s", sanitizedSplits[0], sanitizedSplits[1])))
-	}
-	featStrippedSplits := strings.Split(strings.ReplaceAll(featStrippedTitle, ":", "-"), "-")
-	if len(featStrippedSplits) < 2 {
-		titles = append(titles, featStrippedSplits[0])
-	}
-	if len(featStrippedSplits) == 2 {
-		titles = append(titles, featStrippedSplits[0], featStrippedSplits[1])
-		artists = append(artists, s.SanitizeAuthor(featStrippedSplits[0]), s.SanitizeAuthor(featStrippedSplits[1]))
-	} else if len(featStrippedSplits) == 3 {
-		titles = append(titles, featStrippedSplits[0], featStrippedSplits[1], featStrippedSplits[2], fmt.Sprintf("%s %s", featStrippedSplits[0], featStrippedSplits[1]), fmt.Sprintf("%s %s", featStrippedSplits[1], featStrippedSplits[2]))
-		artists = append(artists, s.SanitizeAuthor(featStrippedSplits[0]), s.SanitizeAuthor(featStrippedSplits[1]), s.SanitizeAuthor(featStrippedSplits[2]), s.SanitizeAuthor(fmt.Sprintf("%s %s", featStrippedSplits[0], featStrippedSplits[1])), s.SanitizeAuthor(fmt.Sprintf("%s %s", featStrippedSplits[1], featStrippedSplits[2])))
-	} else if len(featStrippedSplits) == 4 {
-		titles = append(titles, featStrippedSplits[0], featStrippedSplits[1], featStrippedSplits[2], featStrippedSplits[3], fmt.Sprintf("%s %s", featStrippedSplits[0], featStrippedSplits[1]), fmt.Sprintf("%s %s", featStrippedSplits[1], featStrippedSplits[2]), fmt.Sprintf("%s %s", featStrippedSplits[2], featStrippedSplits[3]), fmt.Sprintf("%s %s %s", featStrippedSplits[0], featStrippedSplits[1], featStrippedSplits[2]), fmt.Sprintf("%s %s %s", featStrippedSplits[1], featStrippedSplits[2], featStrippedSplits[3]), fmt.Sprintf("%s %s", featStrippedSplits[0], featStrippedSplits[1]))
-		artists = append(artists, s.SanitizeAuthor(featStrippedSplits[0]), s.SanitizeAuthor(featStrippedSplits[1]), s.SanitizeAuthor(featStrippedSplits[2]), s.SanitizeAuthor(featStrippedSplits[3]), s.SanitizeAuthor(fmt.Sprintf("%s %s", featStrippedSplits[0], featStrippedSplits[1])), s.SanitizeAuthor(fmt.Sprintf("%s %s", featStrippedSplits[1], featStrippedSplits[2])), s.SanitizeAuthor(fmt.Sprintf("%s %s", featStrippedSplits[2], featStrippedSplits[3])), s.SanitizeAuthor(fmt.Sprintf("%s %s %s", featStrippedSplits[0], featStrippedSplits[1], featStrippedSplits[2])), s.SanitizeAuthor(fmt.Sprintf("%s %s %s", featStrippedSplits[1], featStrippedSplits[2], featStrippedSplits[3])), s.SanitizeAuthor(fmt.Sprintf("%s %s", featStrippedSplits[0], featStrippedSplits[1])))
-	}
-	for i, title := range titles {
-		titles[i] = strings.Trim(strings.ReplaceAll(title, "  ", " "), " ")
-	}
-	for i, artist := range artists {
-		artists[i] = strings.Trim(strings.ReplaceAll(artist, "  ", " "), " ")
-	}
-	zaplog.InfoC(ctx, "titles", zap.Strings("titles", titles))
-	zaplog.InfoC(ctx, "artists", zap.Strings("artists", artists))
-
-	for _, spotifyMeta := range spotifyMetas {
-		if coverArtist != "" {
-			if s.EqualIgnoringWhitespace(coverArtist, spotifyMeta.Artist) {
-				for _, title := range titles {
-					if s.EqualIgnoringWhitespace(title, spotifyMeta.Title) {
-						return TrackMeta{Title: spotifyMeta.Title, Artist: spotifyMeta.Artist, Album: spotifyMeta.Album, CoverArtURL: spotifyMeta.CoverArtURL}
-					}
-				}
+				zaplog.ErrorC(ctx, "failed to get provider meta", zap.String("provider", provider.Name()), zap.Error(err))
+				return s.noMatch(trackMeta, fmt.Sprintf("search failed: %v", err)), nil, false
 			}
+			candidates = append(candidates, caCandidates...)
 		}
-		for _, title := range titles {
-			if s.EqualIgnoringWhitespace(title, spotifyMeta.Title) {
-				for _, artist := range artists {
-					if s.EqualIgnoringWhitespace(artist, spotifyMeta.Artist) {
-						return TrackMeta{Title: spotifyMeta.Title, Artist: spotifyMeta.Artist, Album: spotifyMeta.Album, CoverArtURL: spotifyMeta.CoverArtURL}
-					}
-				}
-			}
+		if len(candidates) == 0 {
+			return s.noMatch(trackMeta, "no results"), nil, false
+		}
+	}
+	scored := s.ScoreCandidates(trackMeta, coverArtist, candidates, time.Duration(s.Config.MetadataDurationToleranceSeconds)*time.Second)
+	for i := range scored {
+		scored[i].Meta.Provider = provider.Name()
+		scored[i].Meta.MatchScore = scored[i].Score
+	}
+	for i, candidate := range scored[:min(len(scored), 3)] {
+		zaplog.InfoC(ctx, "meta candidate", zap.Int("rank", i+1), zap.String("title", candidate.Meta.Title), zap.String("artist", candidate.Meta.Artist),
+			zap.Float64("score", candidate.Score), zap.Float64("titleScore", candidate.TitleScore), zap.Float64("artistScore", candidate.ArtistScore), zap.Strings("penalties", candidate.Penalties))
+	}
+	for _, candidate := range scored {
+		if candidate.Score < s.Config.MetadataMatchThreshold {
+			break
 		}
+		if candidate.DurationMismatch {
+			zaplog.InfoC(ctx, "meta candidate outside duration tolerance", zap.String("title", candidate.Meta.Title), zap.String("artist", candidate.Meta.Artist),
+				zap.Int("videoDurationMs", trackMeta.DurationMs), zap.Int("candidateDurationMs", candidate.Meta.DurationMs))
+			continue
+		}
+		return candidate.Meta, scored, true
+	}
+	best := scored[0]
+	if best.DurationMismatch {
+		return s.noMatch(trackMeta, fmt.Sprintf("best match %q by %s is %s long, the video is %s",
+			best.Meta.Title, best.Meta.Artist, formatDuration(best.Meta.DurationMs), formatDuration(trackMeta.DurationMs))), scored, false
 	}
+	zaplog.InfoC(ctx, "best meta candidate below threshold", zap.Float64("score", best.Score), zap.Float64("threshold", s.Config.MetadataMatchThreshold))
+	return s.noMatch(trackMeta, fmt.Sprintf("best match %q by %s scored %.2f, below the %.2f threshold",
+		best.Meta.Title, best.Meta.Artist, best.Score, s.Config.MetadataMatchThreshold)), scored, false
+}
+
+// noMatch is the fallback metadata with the reason no candidate was used.
+func (s *Service) noMatch(trackMeta TrackMeta, reason string) TrackMeta {
+	fallback := s.FallbackMeta(trackMeta)
+	fallback.MatchNote = reason
+	return fallback
+}
 
-	return TrackMeta{Title: sanitizedTitle, Artist: trackMeta.Artist, Album: sanitizedTitle, CoverArtURL: trackMeta.CoverArtURL}
+func formatDuration(ms int) string {
+	return (time.Duration(ms) * time.Millisecond).Round(time.Second).String()
 }
 
+// SanitizeString strips emoji, symbols and punctuation from a title. Letters and digits of every script are kept
+// as they are, the cleaned title is what the fallback tags and file name are written with.
 func (s *Service) SanitizeString(str string) string {
-	regex := regexp.MustCompile(`[^a-zA-Z0-9\s\:\-]`)
-	return regex.ReplaceAllString(str, "")
+	return textnorm.Clean(str)
 }
 
 func (s *Service) SanitizeParenthesis(str string) string {
@@ -206,16 +355,6 @@ func (s *Service) SanitizeParenthesis(str string) string {
 	return regex.ReplaceAllString(str, "")
 }
 
-func (s *Service) EqualIgnoringWhitespace(s1, s2 string) bool {
-	// Remove all whitespace from both strings
-	regex := regexp.MustCompile(`\s+`)
-	cleanS1 := regex.ReplaceAllString(s1, "")
-	cleanS2 := regex.ReplaceAllString(s2, "")
-
-	// Compare the cleaned strings
-	return strings.EqualFold(cleanS1, cleanS2)
-}
-
 func (s *Service) CoverArtistCheck(ctx context.Context, str string) string {
 	str = strings.ToLower(str)
 	parenthesisReg := regexp.MustCompile(`\([^\(\)]*\)|\[[^\[\]]*\]`)
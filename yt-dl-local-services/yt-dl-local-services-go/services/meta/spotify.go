@@ -0,0 +1,191 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/zmb3/spotify/v2"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// ErrSpotifyRateLimited is returned without calling Spotify while a Retry-After longer than
+// spotify_max_backoff_seconds is in effect, so the track falls through to the next provider instead of waiting.
+var ErrSpotifyRateLimited = errors.New("spotify rate limit in effect")
+
+const (
+	// spotifyMaxAttempts is how often a rate limited request is sent before the 429 is returned
+	spotifyMaxAttempts = 3
+	// spotifyDefaultBackoff is used when a 429 comes without a usable Retry-After
+	spotifyDefaultBackoff = 5 * time.Second
+)
+
+// SpotifyStatus is the health of the Spotify provider shown by the diagnostics endpoint.
+type SpotifyStatus struct {
+	Configured       bool      `json:"configured"`
+	TokenExpiry      time.Time `json:"token_expiry,omitempty"`
+	TokenRefreshes   int       `json:"token_refreshes"`
+	Requests         int       `json:"requests"`
+	RateLimitHits    int       `json:"rate_limit_hits"`
+	RateLimitedUntil time.Time `json:"rate_limited_until,omitempty"`
+	LastSuccess      time.Time `json:"last_success,omitempty"`
+	LastError        string    `json:"last_error,omitempty"`
+	LastErrorAt      time.Time `json:"last_error_at,omitempty"`
+}
+
+// spotifyLimiter is shared by every Spotify request. It caches the client credentials token until it expires and
+// holds all requests back while Spotify's Retry-After is in effect, so a burst of searches from a big playlist
+// backs off together instead of each track failing on its own 429.
+type spotifyLimiter struct {
+	base       http.RoundTripper
+	config     *clientcredentials.Config
+	maxBackoff time.Duration
+
+	mu     sync.Mutex
+	token  *oauth2.Token
+	status SpotifyStatus
+}
+
+// newSpotifyClient returns the one Spotify client the service uses for all searches.
+func (s *Service) newSpotifyClient() *spotify.Client {
+	s.spotifyLimiter = &spotifyLimiter{
+		base:       http.DefaultTransport,
+		config:     s.SpotifyConfig,
+		maxBackoff: time.Duration(s.Config.SpotifyMaxBackoffSeconds) * time.Second,
+		status:     SpotifyStatus{Configured: true},
+	}
+	return spotify.New(&http.Client{Transport: s.spotifyLimiter})
+}
+
+// Token returns the cached token, a new one is only fetched shortly before the cached one expires or after it was
+// rejected. The config's own TokenSource is not used, it would keep returning a rejected token until its expiry.
+func (l *spotifyLimiter) Token() (*oauth2.Token, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.token.Valid() {
+		return l.token, nil
+	}
+	token, err := l.config.Token(context.Background())
+	if err != nil {
+		l.recordError(err)
+		return nil, err
+	}
+	l.token = token
+	l.status.TokenExpiry = token.Expiry
+	l.status.TokenRefreshes++
+	return token, nil
+}
+
+func (l *spotifyLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := l.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spotify token: %w", err)
+	}
+	for attempt := 1; ; attempt++ {
+		if err := l.wait(req.Context()); err != nil {
+			return nil, err
+		}
+		authed := req.Clone(req.Context())
+		token.SetAuthHeader(authed)
+		l.mu.Lock()
+		l.status.Requests++
+		l.mu.Unlock()
+		resp, err := l.base.RoundTrip(authed)
+		if err != nil {
+			l.mu.Lock()
+			l.recordError(err)
+			l.mu.Unlock()
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests {
+			l.mu.Lock()
+			if resp.StatusCode < 400 {
+				l.status.LastSuccess = time.Now()
+			} else {
+				if resp.StatusCode == http.StatusUnauthorized {
+					// the token was rejected before its expiry, the next request fetches a new one
+					l.token = nil
+				}
+				l.recordError(fmt.Errorf("spotify returned %d", resp.StatusCode))
+			}
+			l.mu.Unlock()
+			return resp, nil
+		}
+		backoff := retryAfter(resp.Header.Get("Retry-After"))
+		l.mu.Lock()
+		l.status.RateLimitHits++
+		l.recordError(fmt.Errorf("rate limited for %s", backoff))
+		if until := time.Now().Add(backoff); until.After(l.status.RateLimitedUntil) {
+			l.status.RateLimitedUntil = until
+		}
+		l.mu.Unlock()
+		zaplog.WarnC(req.Context(), "spotify rate limit hit", zap.Duration("retryAfter", backoff), zap.Int("attempt", attempt))
+		if attempt == spotifyMaxAttempts {
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+}
+
+// wait blocks until the rate limit shared by all requests has passed. Waits longer than the configured maximum
+// fail straight away.
+func (l *spotifyLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	delay := time.Until(l.status.RateLimitedUntil)
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	if delay > l.maxBackoff {
+		return fmt.Errorf("%w for another %s", ErrSpotifyRateLimited, delay.Round(time.Second))
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// recordError keeps the last error for the diagnostics, the caller must hold l.mu.
+func (l *spotifyLimiter) recordError(err error) {
+	l.status.LastError = err.Error()
+	l.status.LastErrorAt = time.Now()
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds <= 0 {
+		return spotifyDefaultBackoff
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Diagnostics returns the configured providers and the Spotify health.
+func (s *Service) Diagnostics(ctx context.Context) *Diagnostics {
+	diagnostics := &Diagnostics{Providers: make([]string, 0, len(s.Providers)), Spotify: s.SpotifyStatus()}
+	for _, provider := range s.Providers {
+		diagnostics.Providers = append(diagnostics.Providers, provider.Name())
+	}
+	return diagnostics
+}
+
+// SpotifyStatus returns the current health of the Spotify provider.
+func (s *Service) SpotifyStatus() SpotifyStatus {
+	if s.spotifyLimiter == nil {
+		return SpotifyStatus{}
+	}
+	s.spotifyLimiter.mu.Lock()
+	defer s.spotifyLimiter.mu.Unlock()
+	return s.spotifyLimiter.status
+}
@@ -1,30 +1,195 @@
 package meta
 
 import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gcottom/go-zaplog"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/artists"
 	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/http_client"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/qualifiers"
+	"github.com/zmb3/spotify/v2"
+	spotifyauth "github.com/zmb3/spotify/v2/auth"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
+// MetadataProvider searches a metadata source for tracks matching the title and artist of a YouTube video.
+type MetadataProvider interface {
+	Name() string
+	Search(ctx context.Context, trackMeta TrackMeta) ([]TrackMeta, error)
+}
+
+const (
+	ProviderSpotify     = "spotify"
+	ProviderMusicBrainz = "musicbrainz"
+)
+
+type MetaService interface {
+	Diagnostics(ctx context.Context) *Diagnostics
+	GetCachedMeta(ctx context.Context, id string) (*CacheEntry, error)
+	ListCachedMeta(ctx context.Context) ([]CacheEntry, error)
+	InvalidateCachedMeta(ctx context.Context, id string) error
+	ClearCachedMeta(ctx context.Context) (int, error)
+}
+
 type Service struct {
 	Config        *config.Config
 	HTTPClient    *http_client.HTTPClient
 	SpotifyConfig *clientcredentials.Config
+	Providers     []MetadataProvider
+	Cache         *Cache
+
+	spotifyClient  *spotify.Client
+	spotifyLimiter *spotifyLimiter
+}
+
+func NewMetaService(cfg *config.Config, httpClient *http_client.HTTPClient) (*Service, error) {
+	s := &Service{
+		Config:     cfg,
+		HTTPClient: httpClient,
+		SpotifyConfig: &clientcredentials.Config{
+			ClientID:     cfg.SpotifyClientID,
+			ClientSecret: cfg.SpotifyClientSecret,
+			TokenURL:     spotifyauth.TokenURL,
+		},
+	}
+	for _, name := range cfg.MetadataProviders {
+		switch name {
+		case ProviderSpotify:
+			if cfg.SpotifyClientID == "" || cfg.SpotifyClientSecret == "" {
+				zaplog.Warn("spotify metadata provider skipped, no client credentials configured")
+				continue
+			}
+			s.spotifyClient = s.newSpotifyClient()
+			s.Providers = append(s.Providers, &SpotifyProvider{Service: s})
+		case ProviderMusicBrainz:
+			s.Providers = append(s.Providers, NewMusicBrainzProvider(cfg, httpClient))
+		}
+	}
+	if !cfg.DisableMetaCache {
+		cache, err := NewCache(cfg.StateDir, time.Duration(cfg.MetaCacheTTLHours)*time.Hour)
+		if err != nil {
+			return nil, err
+		}
+		s.Cache = cache
+	}
+	return s, nil
 }
 
 type TrackMeta struct {
-	ID          string `dynamodbav:"id" json:"id"`
-	Status      string `dynamodbav:"status" json:"status,omitempty"`
-	URL         string `dynamodbav:"url" json:"url,omitempty"`
-	Title       string `dynamodbav:"title" json:"title"`
-	Artist      string `dynamodbav:"artist" json:"artist"`
-	Album       string `dynamodbav:"album" json:"album,omitempty"`
-	CoverArtURL string `dynamodbav:"cover_art_url" json:"cover_art_url,omitempty"`
+	ID                        string   `dynamodbav:"id" json:"id"`
+	Status                    string   `dynamodbav:"status" json:"status,omitempty"`
+	URL                       string   `dynamodbav:"url" json:"url,omitempty"`
+	Title                     string   `dynamodbav:"title" json:"title"`
+	Artist                    string   `dynamodbav:"artist" json:"artist"`
+	Artists                   []string `dynamodbav:"artists" json:"artists,omitempty"`
+	FeaturedArtists           []string `dynamodbav:"featured_artists" json:"featured_artists,omitempty"`
+	Album                     string   `dynamodbav:"album" json:"album,omitempty"`
+	Genre                     string   `dynamodbav:"genre" json:"genre,omitempty"`
+	AlbumArtist               string   `dynamodbav:"album_artist" json:"album_artist,omitempty"`
+	TrackNumber               int      `dynamodbav:"track_number" json:"track_number,omitempty"`
+	TrackTotal                int      `dynamodbav:"track_total" json:"track_total,omitempty"`
+	DiscNumber                int      `dynamodbav:"disc_number" json:"disc_number,omitempty"`
+	DiscTotal                 int      `dynamodbav:"disc_total" json:"disc_total,omitempty"`
+	CoverArtURL               string   `dynamodbav:"cover_art_url" json:"cover_art_url,omitempty"`
+	MusicBrainzRecordingID    string   `dynamodbav:"musicbrainz_recording_id" json:"musicbrainz_recording_id,omitempty"`
+	MusicBrainzReleaseID      string   `dynamodbav:"musicbrainz_release_id" json:"musicbrainz_release_id,omitempty"`
+	MusicBrainzReleaseGroupID string   `dynamodbav:"musicbrainz_release_group_id" json:"musicbrainz_release_group_id,omitempty"`
+	MusicBrainzArtistIDs      []string `dynamodbav:"musicbrainz_artist_ids" json:"musicbrainz_artist_ids,omitempty"`
+	ReleaseDate               string   `dynamodbav:"release_date" json:"release_date,omitempty"`
+	Year                      int      `dynamodbav:"year" json:"year,omitempty"`
+	ISRC                      string   `dynamodbav:"isrc" json:"isrc,omitempty"`
+	Composer                  string   `dynamodbav:"composer" json:"composer,omitempty"`
+	Explicit                  bool     `dynamodbav:"explicit" json:"explicit,omitempty"`
+	DurationMs                int      `dynamodbav:"duration_ms" json:"duration_ms,omitempty"`
+	SpotifyTrackID            string   `dynamodbav:"spotify_track_id" json:"spotify_track_id,omitempty"`
+	SpotifyAlbumID            string   `dynamodbav:"spotify_album_id" json:"spotify_album_id,omitempty"`
+	SpotifyArtistIDs          []string `dynamodbav:"spotify_artist_ids" json:"spotify_artist_ids,omitempty"`
+	Lyrics                    string   `dynamodbav:"lyrics" json:"lyrics,omitempty"`
+	SyncedLyrics              string   `dynamodbav:"synced_lyrics" json:"synced_lyrics,omitempty"`
+	MatchNote                 string   `dynamodbav:"match_note" json:"match_note,omitempty"`
+	// Provider and MatchScore are the provider a candidate came from and its match confidence, empty for YouTube
+	Provider   string  `dynamodbav:"provider" json:"provider,omitempty"`
+	MatchScore float64 `dynamodbav:"match_score" json:"match_score,omitempty"`
+	// Version holds the remix, live and other markers of the title, nil for the original recording
+	Version *qualifiers.Version `dynamodbav:"version" json:"version,omitempty"`
+}
+
+// Diagnostics reports the configured providers and the health of the ones that track it.
+type Diagnostics struct {
+	Providers []string      `json:"providers"`
+	Spotify   SpotifyStatus `json:"spotify"`
+}
+
+// MetaResult is the outcome of resolving a video's metadata. Meta is the provider match, or the YouTube fallback
+// when Matched is false, in which case Candidates holds every scored provider result best first. Incomplete is set
+// when a provider failed to answer, such a result is not cached.
+type MetaResult struct {
+	Meta        *TrackMeta
+	Matched     bool
+	YoutubeMeta TrackMeta
+	Candidates  []ScoredMeta
+	Incomplete  bool
+}
+
+// MatchNoteOverridden is the match note of metadata given outright with the request.
+const MatchNoteOverridden = "overridden"
+
+// Overrides are metadata values given with a download request. They win over whatever is resolved for the track,
+// and a genre override also replaces the genre classifier's result.
+type Overrides struct {
+	Title       string `json:"title,omitempty" form:"title"`
+	Artist      string `json:"artist,omitempty" form:"artist"`
+	Album       string `json:"album,omitempty" form:"album"`
+	Genre       string `json:"genre,omitempty" form:"genre"`
+	Year        int    `json:"year,omitempty" form:"year"`
+	CoverArtURL string `json:"cover_url,omitempty" form:"cover_url"`
+}
+
+// IdentifiesTrack reports whether the overrides name the track outright, in which case provider matching is skipped.
+func (o *Overrides) IdentifiesTrack() bool {
+	return o != nil && o.Title != "" && o.Artist != ""
+}
+
+// Apply writes the given overrides over the track's metadata.
+func (o *Overrides) Apply(trackMeta *TrackMeta) {
+	if o == nil {
+		return
+	}
+	if o.Title != "" {
+		trackMeta.Title = o.Title
+	}
+	if o.Artist != "" {
+		// the primary artists are taken as written, only a feat. or with marker splits the credit
+		primary, featured := artists.Split(o.Artist)
+		credit := artists.Credit{Primary: []string{primary}}
+		credit.AddFeatured(artists.Parse(featured).All()...)
+		trackMeta.Artist = o.Artist
+		trackMeta.Artists = credit.All()
+		trackMeta.FeaturedArtists = credit.Featured
+	}
+	if o.Album != "" {
+		trackMeta.Album = o.Album
+	}
+	if o.Genre != "" {
+		trackMeta.Genre = o.Genre
+	}
+	if o.Year > 0 && o.Year != trackMeta.Year {
+		// a release date from another year would contradict the override in the tags
+		trackMeta.Year = o.Year
+		trackMeta.ReleaseDate = strconv.Itoa(o.Year)
+	}
+	if o.CoverArtURL != "" {
+		trackMeta.CoverArtURL = o.CoverArtURL
+	}
 }
 
 type YTMMetaResponse struct {
-	Title  string `json:"title"`
-	Author string `json:"author"`
-	Image  string `json:"image"`
-	Type   string `json:"type"`
+	Title    string `json:"title"`
+	Author   string `json:"author"`
+	Image    string `json:"image"`
+	Type     string `json:"type"`
+	Duration int    `json:"duration"`
 }
@@ -0,0 +1,129 @@
+package playlists
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+func renderM3U8(playlist *Playlist, tracks []Track) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString("#EXTM3U\n")
+	fmt.Fprintf(buf, "#PLAYLIST:%s\n", oneLine(displayTitle(playlist)))
+	for _, track := range tracks {
+		if track.Location == "" {
+			continue
+		}
+		seconds := int(track.Duration.Seconds())
+		if seconds <= 0 {
+			seconds = -1
+		}
+		fmt.Fprintf(buf, "#EXTINF:%d,%s\n", seconds, oneLine(displayName(track)))
+		buf.WriteString(track.Location + "\n")
+	}
+	return buf.Bytes()
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Creator string      `xml:"creator,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location   string `xml:"location,omitempty"`
+	Identifier string `xml:"identifier"`
+	Title      string `xml:"title,omitempty"`
+	Creator    string `xml:"creator,omitempty"`
+	Album      string `xml:"album,omitempty"`
+	Duration   int64  `xml:"duration,omitempty"`
+}
+
+func renderXSPF(playlist *Playlist, tracks []Track) ([]byte, error) {
+	doc := xspfPlaylist{Version: "1", XMLNS: "http://xspf.org/ns/0/", Title: displayTitle(playlist), Creator: playlist.Author}
+	for _, track := range tracks {
+		doc.Tracks = append(doc.Tracks, xspfTrack{
+			Location:   locationURI(track.Location),
+			Identifier: videoURL(track.ID),
+			Title:      track.Title,
+			Creator:    track.Artist,
+			Album:      track.Album,
+			Duration:   track.Duration.Milliseconds(),
+		})
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+type jspfDocument struct {
+	Playlist jspfPlaylist `json:"playlist"`
+}
+
+type jspfPlaylist struct {
+	Title   string      `json:"title,omitempty"`
+	Creator string      `json:"creator,omitempty"`
+	Track   []jspfTrack `json:"track"`
+}
+
+type jspfTrack struct {
+	Location   []string `json:"location,omitempty"`
+	Identifier []string `json:"identifier"`
+	Title      string   `json:"title,omitempty"`
+	Creator    string   `json:"creator,omitempty"`
+	Album      string   `json:"album,omitempty"`
+	Duration   int64    `json:"duration,omitempty"`
+}
+
+func renderJSPF(playlist *Playlist, tracks []Track) ([]byte, error) {
+	doc := jspfDocument{Playlist: jspfPlaylist{Title: displayTitle(playlist), Creator: playlist.Author, Track: make([]jspfTrack, 0, len(tracks))}}
+	for _, track := range tracks {
+		out := jspfTrack{
+			Identifier: []string{videoURL(track.ID)},
+			Title:      track.Title,
+			Creator:    track.Artist,
+			Album:      track.Album,
+			Duration:   track.Duration.Milliseconds(),
+		}
+		if track.Location != "" {
+			out.Location = []string{locationURI(track.Location)}
+		}
+		doc.Playlist.Track = append(doc.Playlist.Track, out)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func displayName(track Track) string {
+	if track.Artist == "" {
+		return track.Title
+	}
+	return fmt.Sprintf("%s - %s", track.Artist, track.Title)
+}
+
+// locationURI escapes a relative path for use as a URI reference in XSPF and JSPF.
+func locationURI(path string) string {
+	if path == "" {
+		return ""
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func videoURL(id string) string {
+	return fmt.Sprintf("https://www.youtube.com/watch?v=%s", id)
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,136 @@
+package playlists
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/filestore"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/pathtemplate"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/youtube_v2"
+	"go.uber.org/zap"
+)
+
+// SavePlaylist stores the playlist manifest and reports whether its title or entries changed since it was last seen.
+func (s *Service) SavePlaylist(ctx context.Context, info *youtube_v2.PlaylistInfo) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.Playlists[info.ID]
+	if ok && existing.Title == info.Title && slices.Equal(existing.EntryIDs(), info.EntryIDs()) {
+		return false, nil
+	}
+	playlist := &Playlist{PlaylistInfo: *info, UpdatedAt: time.Now()}
+	playlist.Entries = slices.Clone(info.Entries)
+	if ok {
+		playlist.FileName = existing.FileName
+	}
+	s.Playlists[info.ID] = playlist
+	zaplog.InfoC(ctx, "playlist manifest saved", zap.String("playlistID", info.ID), zap.Int("count", len(info.Entries)))
+	return true, s.save()
+}
+
+// WritePlaylistFile writes the playlist as an extended M3U file into the save dir, named after the playlist
+// title. Entries are written in the original playlist order with paths relative to the save dir, entries that
+// have not been downloaded are left out.
+func (s *Service) WritePlaylistFile(ctx context.Context, id string) (string, error) {
+	s.mu.Lock()
+	playlist, ok := s.Playlists[id]
+	if !ok {
+		s.mu.Unlock()
+		return "", ErrNotFound
+	}
+	if playlist.FileName == "" {
+		playlist.FileName = s.fileName(playlist)
+		if err := s.save(); err != nil {
+			s.mu.Unlock()
+			return "", err
+		}
+	}
+	out := *playlist
+	s.mu.Unlock()
+
+	path := filepath.Join(s.Config.SaveDir, out.FileName)
+	if err := os.MkdirAll(s.Config.SaveDir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, renderM3U8(&out, s.tracks(&out)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write playlist file: %w", err)
+	}
+	zaplog.InfoC(ctx, "playlist file written", zap.String("playlistID", id), zap.String("path", path))
+	return path, nil
+}
+
+func (s *Service) Export(ctx context.Context, id string, format string) (*Export, error) {
+	s.mu.Lock()
+	playlist, ok := s.Playlists[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	out := *playlist
+	s.mu.Unlock()
+
+	name := pathtemplate.SanitizeComponent(displayTitle(&out))
+	tracks := s.tracks(&out)
+	switch format {
+	case FormatM3U8, "":
+		return &Export{FileName: name + ".m3u8", ContentType: "audio/x-mpegurl", Data: renderM3U8(&out, tracks)}, nil
+	case FormatXSPF:
+		data, err := renderXSPF(&out, tracks)
+		if err != nil {
+			return nil, err
+		}
+		return &Export{FileName: name + ".xspf", ContentType: "application/xspf+xml", Data: data}, nil
+	case FormatJSPF:
+		data, err := renderJSPF(&out, tracks)
+		if err != nil {
+			return nil, err
+		}
+		return &Export{FileName: name + ".jspf", ContentType: "application/json", Data: data}, nil
+	}
+	return nil, fmt.Errorf("unsupported playlist format %q, expected %s, %s or %s", format, FormatM3U8, FormatXSPF, FormatJSPF)
+}
+
+// tracks joins the playlist entries with the library so every entry knows where, and whether, it was saved.
+func (s *Service) tracks(playlist *Playlist) []Track {
+	tracks := make([]Track, 0, len(playlist.Entries))
+	for _, entry := range playlist.Entries {
+		track := Track{ID: entry.ID, Title: entry.Title, Artist: entry.Author, Duration: entry.Duration}
+		if libraryEntry, ok := s.LibraryService.FindByYoutubeID(entry.ID); ok {
+			track.Location = libraryEntry.Path
+			track.Title = libraryEntry.Title
+			track.Artist = libraryEntry.Artist
+			track.Album = libraryEntry.Album
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks
+}
+
+// fileName picks the name of the playlist file once, so a renamed playlist keeps writing to the same file. Two
+// playlists with the same title are told apart by the playlist ID. The caller must hold s.mu.
+func (s *Service) fileName(playlist *Playlist) string {
+	name := pathtemplate.SanitizeComponent(displayTitle(playlist)) + ".m3u8"
+	for id, other := range s.Playlists {
+		if id != playlist.ID && other.FileName == name {
+			return pathtemplate.WithSuffix(name, fmt.Sprintf(" [%s]", playlist.ID))
+		}
+	}
+	return name
+}
+
+// save persists the manifests, the caller must hold s.mu.
+func (s *Service) save() error {
+	return filestore.Save(s.path, s.Playlists)
+}
+
+func displayTitle(playlist *Playlist) string {
+	if playlist.Title != "" {
+		return playlist.Title
+	}
+	return playlist.ID
+}
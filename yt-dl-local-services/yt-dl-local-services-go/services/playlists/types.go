@@ -0,0 +1,73 @@
+package playlists
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/filestore"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/youtube_v2"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/library"
+)
+
+var ErrNotFound = errors.New("playlist not found")
+
+type PlaylistService interface {
+	Export(ctx context.Context, id string, format string) (*Export, error)
+}
+
+type Service struct {
+	Config         *config.Config
+	LibraryService *library.Service
+
+	mu        sync.Mutex
+	path      string
+	Playlists map[string]*Playlist
+}
+
+func NewPlaylistService(cfg *config.Config, libraryService *library.Service) (*Service, error) {
+	s := &Service{
+		Config:         cfg,
+		LibraryService: libraryService,
+		path:           filepath.Join(cfg.StateDir, "playlists.json"),
+		Playlists:      make(map[string]*Playlist),
+	}
+	if err := filestore.Load(s.path, &s.Playlists); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Playlist is the stored manifest of a downloaded playlist, it keeps the original order so playlist files can be
+// written and exported long after the download finished.
+type Playlist struct {
+	youtube_v2.PlaylistInfo
+	FileName  string    `json:"file_name,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Track is a playlist entry joined with the library file it was saved to. Location is empty for entries that
+// have not been downloaded.
+type Track struct {
+	ID       string
+	Title    string
+	Artist   string
+	Album    string
+	Duration time.Duration
+	Location string
+}
+
+type Export struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+const (
+	FormatM3U8 = "m3u8"
+	FormatXSPF = "xspf"
+	FormatJSPF = "jspf"
+)
@@ -0,0 +1,254 @@
+package retag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/audiotags"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/coverart"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+	"go.uber.org/zap"
+)
+
+// identifierFields are replaced along with the match even when the new value is empty, IDs left over from an
+// earlier wrong match would point other taggers at the wrong release. Every other field keeps its old value when
+// the new match has none.
+var identifierFields = map[string]bool{
+	audiotags.FieldExplicit:                  true,
+	audiotags.FieldSpotifyTrackID:            true,
+	audiotags.FieldSpotifyAlbumID:            true,
+	audiotags.FieldSpotifyArtistID:           true,
+	audiotags.FieldMusicBrainzRecordingID:    true,
+	audiotags.FieldMusicBrainzAlbumID:        true,
+	audiotags.FieldMusicBrainzReleaseGroupID: true,
+	audiotags.FieldMusicBrainzArtistID:       true,
+}
+
+// Retag re-resolves the metadata of library files and rewrites their tags in place.
+func (s *Service) Retag(ctx context.Context, req Request) (*Result, error) {
+	paths, err := s.selectFiles(req)
+	if err != nil {
+		return nil, err
+	}
+	zaplog.InfoC(ctx, "retagging files", zap.Int("count", len(paths)), zap.Bool("dryRun", req.DryRun))
+	result := &Result{DryRun: req.DryRun, Files: make([]FileResult, 0, len(paths))}
+	for _, path := range paths {
+		file := s.retagFile(ctx, path, req)
+		switch file.Status {
+		case StatusChanged:
+			result.Changed++
+		case StatusUnchanged:
+			result.Unchanged++
+		case StatusSkipped:
+			result.Skipped++
+		case StatusFailed:
+			result.Failed++
+		}
+		result.Files = append(result.Files, file)
+	}
+	zaplog.InfoC(ctx, "retag complete", zap.Int("changed", result.Changed), zap.Int("unchanged", result.Unchanged),
+		zap.Int("skipped", result.Skipped), zap.Int("failed", result.Failed), zap.Bool("dryRun", req.DryRun))
+	return result, nil
+}
+
+// selectFiles returns the absolute paths of the requested files.
+func (s *Service) selectFiles(req Request) ([]string, error) {
+	if len(req.Paths) == 0 && req.Filter == nil {
+		return nil, errors.New("paths or a filter are required")
+	}
+	paths := make([]string, 0, len(req.Paths))
+	for _, path := range req.Paths {
+		abs, err := s.absolutePath(path)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, abs)
+	}
+	if req.Filter != nil {
+		for _, entry := range s.LibraryService.Find(*req.Filter) {
+			paths = append(paths, filepath.Join(s.Config.SaveDir, filepath.FromSlash(entry.Path)))
+		}
+	}
+	if len(paths) > maxFiles {
+		return nil, fmt.Errorf("%d files selected, at most %d can be retagged at once", len(paths), maxFiles)
+	}
+	return paths, nil
+}
+
+// absolutePath resolves a path relative to the save dir and rejects anything outside of it.
+func (s *Service) absolutePath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.Config.SaveDir, filepath.FromSlash(path))
+	}
+	rel, err := filepath.Rel(s.Config.SaveDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside the save dir", path)
+	}
+	return filepath.Clean(path), nil
+}
+
+func (s *Service) retagFile(ctx context.Context, path string, req Request) FileResult {
+	file := FileResult{Path: path}
+	if rel, err := filepath.Rel(s.Config.SaveDir, path); err == nil {
+		file.Path = filepath.ToSlash(rel)
+	}
+	if entry, ok := s.LibraryService.GetEntry(path); ok {
+		file.YoutubeID = entry.YoutubeID
+	}
+	oldFields, err := audiotags.ReadFields(path)
+	if errors.Is(err, audiotags.ErrUnsupportedFormat) {
+		file.Status, file.Reason = StatusSkipped, err.Error()
+		return file
+	}
+	if err != nil {
+		zaplog.ErrorC(ctx, "failed to read tags", zap.String("path", path), zap.Error(err))
+		file.Status, file.Reason = StatusFailed, fmt.Sprintf("failed to read tags: %v", err)
+		return file
+	}
+
+	var result *meta.MetaResult
+	if file.YoutubeID != "" {
+		file.Source = SourceYoutube
+		if req.Refresh {
+			if err := s.MetaService.InvalidateCachedMeta(ctx, file.YoutubeID); err != nil && !errors.Is(err, meta.ErrNotCached) && !errors.Is(err, meta.ErrCacheDisabled) {
+				zaplog.ErrorC(ctx, "failed to invalidate cached meta", zap.String("id", file.YoutubeID), zap.Error(err))
+			}
+		}
+		result, err = s.MetaService.GetBestMeta(ctx, file.YoutubeID, nil)
+		if err != nil {
+			zaplog.WarnC(ctx, "failed to resolve meta from youtube id, using existing tags", zap.String("path", path), zap.String("id", file.YoutubeID), zap.Error(err))
+		}
+	}
+	if result == nil {
+		if oldFields[audiotags.FieldTitle] == "" {
+			file.Status, file.Reason = StatusSkipped, "file has no youtube id and no title tag to match"
+			return file
+		}
+		file.Source = SourceTags
+		durationMs, _ := strconv.Atoi(oldFields[audiotags.FieldLength])
+		result, err = s.MetaService.ResolveMeta(ctx, meta.TrackMeta{
+			Title:      oldFields[audiotags.FieldTitle],
+			Artist:     oldFields[audiotags.FieldArtist],
+			DurationMs: durationMs,
+		})
+		if err != nil {
+			zaplog.ErrorC(ctx, "failed to resolve meta from tags", zap.String("path", path), zap.Error(err))
+			file.Status, file.Reason = StatusFailed, fmt.Sprintf("failed to resolve metadata: %v", err)
+			return file
+		}
+	}
+	if !result.Matched {
+		file.Status, file.Reason = StatusSkipped, fmt.Sprintf("no confident match: %s", result.Meta.MatchNote)
+		return file
+	}
+
+	s.MetaService.ApplyArtistStyle(result.Meta)
+	newFields := fieldsFromMeta(result.Meta)
+	for _, field := range audiotags.FieldNames {
+		value, ok := newFields[field]
+		if !ok {
+			continue
+		}
+		if value == "" && !identifierFields[field] {
+			delete(newFields, field)
+			continue
+		}
+		if value == oldFields[field] {
+			delete(newFields, field)
+			continue
+		}
+		file.Changes = append(file.Changes, Change{Field: field, Old: oldFields[field], New: value})
+	}
+	var cover *audiotags.Picture
+	if req.ReplaceCover && result.Meta.CoverArtURL != "" {
+		cover, err = s.fetchCover(ctx, result.Meta.CoverArtURL)
+		if err != nil {
+			zaplog.ErrorC(ctx, "failed to fetch cover art", zap.String("url", result.Meta.CoverArtURL), zap.Error(err))
+			file.Status, file.Reason = StatusFailed, fmt.Sprintf("failed to fetch cover art: %v", err)
+			return file
+		}
+		file.Changes = append(file.Changes, Change{Field: "cover", New: result.Meta.CoverArtURL})
+	}
+	if len(file.Changes) == 0 {
+		file.Status = StatusUnchanged
+		return file
+	}
+	file.Status = StatusChanged
+	if req.DryRun {
+		return file
+	}
+	if err := audiotags.WriteFields(path, newFields, cover); err != nil {
+		zaplog.ErrorC(ctx, "failed to write tags", zap.String("path", path), zap.Error(err))
+		file.Status, file.Reason = StatusFailed, fmt.Sprintf("failed to write tags: %v", err)
+		return file
+	}
+	zaplog.InfoC(ctx, "file retagged", zap.String("path", path), zap.Int("changes", len(file.Changes)))
+	if err := s.LibraryService.AddFile(ctx, path, file.YoutubeID); err != nil {
+		zaplog.ErrorC(ctx, "failed to update library entry", zap.String("path", path), zap.Error(err))
+	}
+	return file
+}
+
+// fieldsFromMeta lays out resolved metadata the way the Lambda tags a processed file.
+func fieldsFromMeta(trackMeta *meta.TrackMeta) audiotags.Fields {
+	fields := audiotags.Fields{
+		audiotags.FieldTitle:                     trackMeta.Title,
+		audiotags.FieldArtist:                    trackMeta.Artist,
+		audiotags.FieldArtists:                   strings.Join(trackMeta.Artists, audiotags.ValueSeparator),
+		audiotags.FieldAlbum:                     trackMeta.Album,
+		audiotags.FieldAlbumArtist:               trackMeta.AlbumArtist,
+		audiotags.FieldGenre:                     trackMeta.Genre,
+		audiotags.FieldDate:                      trackMeta.ReleaseDate,
+		audiotags.FieldTrack:                     position(trackMeta.TrackNumber, trackMeta.TrackTotal),
+		audiotags.FieldDisc:                      position(trackMeta.DiscNumber, trackMeta.DiscTotal),
+		audiotags.FieldComposer:                  trackMeta.Composer,
+		audiotags.FieldISRC:                      trackMeta.ISRC,
+		audiotags.FieldSpotifyTrackID:            trackMeta.SpotifyTrackID,
+		audiotags.FieldSpotifyAlbumID:            trackMeta.SpotifyAlbumID,
+		audiotags.FieldSpotifyArtistID:           strings.Join(trackMeta.SpotifyArtistIDs, "/"),
+		audiotags.FieldMusicBrainzRecordingID:    trackMeta.MusicBrainzRecordingID,
+		audiotags.FieldMusicBrainzAlbumID:        trackMeta.MusicBrainzReleaseID,
+		audiotags.FieldMusicBrainzReleaseGroupID: trackMeta.MusicBrainzReleaseGroupID,
+		audiotags.FieldMusicBrainzArtistID:       strings.Join(trackMeta.MusicBrainzArtistIDs, "/"),
+	}
+	if fields[audiotags.FieldDate] == "" && trackMeta.Year > 0 {
+		fields[audiotags.FieldDate] = strconv.Itoa(trackMeta.Year)
+	}
+	if trackMeta.DurationMs > 0 {
+		fields[audiotags.FieldLength] = strconv.Itoa(trackMeta.DurationMs)
+	}
+	fields[audiotags.FieldExplicit] = ""
+	if trackMeta.Explicit {
+		fields[audiotags.FieldExplicit] = "1"
+	}
+	return fields
+}
+
+// position formats a track or disc number as "n/total", or just "n" when the total is unknown.
+func position(n int, total int) string {
+	if n <= 0 {
+		return ""
+	}
+	if total <= 0 {
+		return strconv.Itoa(n)
+	}
+	return fmt.Sprintf("%d/%d", n, total)
+}
+
+// fetchCover downloads the largest variant of the cover and processes it the way the Lambda embeds covers.
+func (s *Service) fetchCover(ctx context.Context, url string) (*audiotags.Picture, error) {
+	data, err := coverart.Fetch(ctx, s.HTTPClient.Client, url, s.Config.CoverArtMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	data, err = coverart.Process(data, coverart.Options{MaxSize: s.Config.CoverArtMaxSize, MaxBytes: s.Config.CoverArtMaxBytes})
+	if err != nil {
+		return nil, err
+	}
+	return &audiotags.Picture{MimeType: "image/jpeg", Data: data}, nil
+}
@@ -0,0 +1,80 @@
+package retag
+
+import (
+	"context"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/http_client"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/library"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/meta"
+)
+
+type RetagService interface {
+	Retag(ctx context.Context, req Request) (*Result, error)
+}
+
+type Service struct {
+	Config         *config.Config
+	HTTPClient     *http_client.HTTPClient
+	LibraryService *library.Service
+	MetaService    *meta.Service
+}
+
+func NewRetagService(cfg *config.Config, httpClient *http_client.HTTPClient, libraryService *library.Service, metaService *meta.Service) *Service {
+	return &Service{
+		Config:         cfg,
+		HTTPClient:     httpClient,
+		LibraryService: libraryService,
+		MetaService:    metaService,
+	}
+}
+
+// Request selects the files to retag, either by path relative to the save dir or by a library filter. With DryRun
+// set the new tags are only compared against the old ones and nothing is written. Files with a YouTube ID reuse
+// the cached metadata of the video unless Refresh is set, so an apply writes what the dry run before it showed.
+type Request struct {
+	Paths        []string       `json:"paths,omitempty"`
+	Filter       *library.Query `json:"filter,omitempty"`
+	DryRun       bool           `json:"dry_run"`
+	ReplaceCover bool           `json:"replace_cover"`
+	Refresh      bool           `json:"refresh"`
+}
+
+type Result struct {
+	DryRun    bool         `json:"dry_run"`
+	Changed   int          `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+	Skipped   int          `json:"skipped"`
+	Failed    int          `json:"failed"`
+	Files     []FileResult `json:"files"`
+}
+
+// FileResult is the diff for a single file. Source tells whether the metadata was resolved from the stored
+// YouTube ID or from the file's existing tags.
+type FileResult struct {
+	Path      string   `json:"path"`
+	YoutubeID string   `json:"youtube_id,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Status    string   `json:"status"`
+	Changes   []Change `json:"changes,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+}
+
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+const (
+	SourceYoutube = "youtube"
+	SourceTags    = "tags"
+
+	StatusChanged   = "changed"
+	StatusUnchanged = "unchanged"
+	StatusSkipped   = "skipped"
+	StatusFailed    = "failed"
+
+	// maxFiles keeps a single request from resolving the whole library, every file costs provider requests
+	maxFiles = 200
+)
@@ -0,0 +1,260 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gcottom/go-zaplog"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/filestore"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/pathtemplate"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/downloader"
+	"go.uber.org/zap"
+)
+
+func (s *Service) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subs := make([]Subscription, 0, len(s.Subscriptions))
+	for _, sub := range s.Subscriptions {
+		subs = append(subs, sub.copy())
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].PlaylistID < subs[j].PlaylistID })
+	return subs, nil
+}
+
+func (s *Service) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.Subscriptions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := sub.copy()
+	return &out, nil
+}
+
+func (s *Service) CreateSubscription(ctx context.Context, req SubscriptionRequest) (*Subscription, error) {
+	if req.PlaylistID == "" {
+		return nil, errors.New("playlist_id is required")
+	}
+	interval, err := s.validateInterval(req.IntervalMinutes)
+	if err != nil {
+		return nil, err
+	}
+	sub := &Subscription{PlaylistID: req.PlaylistID, IntervalMinutes: interval, CreatedAt: time.Now(), KnownEntries: make([]string, 0)}
+	if req.Paused != nil {
+		sub.Paused = *req.Paused
+	}
+	if req.Template != nil {
+		if err := validateTemplate(*req.Template); err != nil {
+			return nil, err
+		}
+		sub.Template = *req.Template
+	}
+	if req.PlaylistAsAlbum != nil {
+		sub.PlaylistAsAlbum = req.PlaylistAsAlbum
+	}
+	if req.SkipExisting {
+		entries, err := s.YoutubeClient.GetPlaylistEntries(ctx, req.PlaylistID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get playlist entries: %w", err)
+		}
+		sub.KnownEntries = entries
+		sub.LastSyncAt = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Subscriptions[req.PlaylistID]; ok {
+		return nil, ErrExists
+	}
+	s.Subscriptions[req.PlaylistID] = sub
+	if err := s.save(); err != nil {
+		delete(s.Subscriptions, req.PlaylistID)
+		return nil, err
+	}
+	zaplog.InfoC(ctx, "subscription created", zap.String("playlistID", sub.PlaylistID), zap.Int("intervalMinutes", sub.IntervalMinutes))
+	out := sub.copy()
+	return &out, nil
+}
+
+func (s *Service) UpdateSubscription(ctx context.Context, id string, req SubscriptionRequest) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.Subscriptions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if req.IntervalMinutes != 0 {
+		interval, err := s.validateInterval(req.IntervalMinutes)
+		if err != nil {
+			return nil, err
+		}
+		sub.IntervalMinutes = interval
+	}
+	if req.Paused != nil {
+		sub.Paused = *req.Paused
+	}
+	if req.Template != nil {
+		if err := validateTemplate(*req.Template); err != nil {
+			return nil, err
+		}
+		sub.Template = *req.Template
+	}
+	if req.PlaylistAsAlbum != nil {
+		sub.PlaylistAsAlbum = req.PlaylistAsAlbum
+	}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	out := sub.copy()
+	return &out, nil
+}
+
+func (s *Service) DeleteSubscription(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Subscriptions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.Subscriptions, id)
+	zaplog.InfoC(ctx, "subscription deleted", zap.String("playlistID", id))
+	return s.save()
+}
+
+// SyncSubscription fetches the playlist, works out which entries are neither known to this subscription nor
+// recorded in the download archive, and queues at most subscription_max_new_entries_per_run of them. Queued
+// entries only become known once the archive has them. Entries still downloading are skipped, a failed download
+// is queued again by the next run until it has been tried maxEntryAttempts times. Entries over the limit are left
+// for the next run so a large backlog is spread out instead of risking a ban.
+func (s *Service) SyncSubscription(ctx context.Context, id string) (*Subscription, error) {
+	if _, err := s.GetSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+	zaplog.InfoC(ctx, "syncing subscription", zap.String("playlistID", id))
+	info, fetchErr := s.YoutubeClient.GetPlaylistInfo(ctx, id)
+
+	s.mu.Lock()
+	sub, ok := s.Subscriptions[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	sub.LastSyncAt = time.Now()
+	if fetchErr != nil {
+		sub.LastSyncError = fetchErr.Error()
+		sub.LastSyncQueued = 0
+		if err := s.save(); err != nil {
+			zaplog.ErrorC(ctx, "failed to save subscriptions", zap.Error(err))
+		}
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to get playlist entries: %w", fetchErr)
+	}
+	known := make(map[string]bool, len(sub.KnownEntries))
+	for _, entry := range sub.KnownEntries {
+		known[entry] = true
+	}
+	entries := info.EntryIDs()
+	newEntries := make([]string, 0)
+	for _, entry := range entries {
+		if known[entry] {
+			continue
+		}
+		known[entry] = true
+		if s.Downloader.IsDownloaded(entry) {
+			sub.KnownEntries = append(sub.KnownEntries, entry)
+			delete(sub.Attempts, entry)
+			continue
+		}
+		if sub.Attempts[entry] >= maxEntryAttempts || s.Downloader.IsInProgress(entry) {
+			continue
+		}
+		if len(newEntries) < s.Config.SubscriptionMaxNewEntriesPerRun {
+			newEntries = append(newEntries, entry)
+			if sub.Attempts == nil {
+				sub.Attempts = make(map[string]int)
+			}
+			sub.Attempts[entry]++
+		}
+	}
+	sub.LastSyncError = ""
+	sub.LastSyncQueued = len(newEntries)
+	if err := s.save(); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	out := sub.copy()
+	s.mu.Unlock()
+
+	zaplog.InfoC(ctx, "subscription synced", zap.String("playlistID", id), zap.Int("playlistCount", len(entries)), zap.Int("queued", len(newEntries)))
+	if err := s.Downloader.QueuePlaylistEntries(ctx, info, newEntries, downloader.DownloadOptions{Template: out.Template, PlaylistAsAlbum: out.PlaylistAsAlbum}); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Scheduler periodically syncs every subscription whose interval has elapsed. Syncs run one at a time.
+func (s *Service) Scheduler() {
+	ctx := context.Background()
+	for {
+		for _, id := range s.dueSubscriptions(time.Now()) {
+			if _, err := s.SyncSubscription(ctx, id); err != nil {
+				zaplog.ErrorC(ctx, "scheduled subscription sync failed", zap.String("playlistID", id), zap.Error(err))
+			}
+		}
+		time.Sleep(schedulerTick)
+	}
+}
+
+func (s *Service) dueSubscriptions(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := make([]string, 0)
+	for id, sub := range s.Subscriptions {
+		if sub.Paused {
+			continue
+		}
+		if now.Sub(sub.LastSyncAt) >= time.Duration(sub.IntervalMinutes)*time.Minute {
+			due = append(due, id)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+func (s *Service) validateInterval(minutes int) (int, error) {
+	if minutes == 0 {
+		minutes = max(defaultIntervalMinutes, s.Config.SubscriptionMinIntervalMinutes)
+	}
+	if minutes < s.Config.SubscriptionMinIntervalMinutes {
+		return 0, fmt.Errorf("interval_minutes must be at least %d", s.Config.SubscriptionMinIntervalMinutes)
+	}
+	return minutes, nil
+}
+
+// save persists the subscriptions, the caller must hold s.mu.
+func (s *Service) save() error {
+	return filestore.Save(s.path, s.Subscriptions)
+}
+
+func validateTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	if err := pathtemplate.Validate(template); err != nil {
+		return fmt.Errorf("invalid filename template: %w", err)
+	}
+	return nil
+}
+
+func (sub *Subscription) copy() Subscription {
+	out := *sub
+	out.KnownEntries = slices.Clone(sub.KnownEntries)
+	out.Attempts = maps.Clone(sub.Attempts)
+	return out
+}
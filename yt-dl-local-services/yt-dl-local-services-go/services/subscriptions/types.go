@@ -0,0 +1,91 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/config"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/filestore"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/pkg/youtube_v2"
+	"github.com/gcottom/yt-dl-3-hybrid/yd-dl-local-services/yt-dl-local-services-go/services/downloader"
+)
+
+var ErrNotFound = errors.New("subscription not found")
+var ErrExists = errors.New("subscription already exists")
+
+type SubscriptionService interface {
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	GetSubscription(ctx context.Context, id string) (*Subscription, error)
+	CreateSubscription(ctx context.Context, req SubscriptionRequest) (*Subscription, error)
+	UpdateSubscription(ctx context.Context, id string, req SubscriptionRequest) (*Subscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	SyncSubscription(ctx context.Context, id string) (*Subscription, error)
+}
+
+// Downloader is the part of the downloader service that subscriptions queue work through.
+type Downloader interface {
+	QueuePlaylistEntries(ctx context.Context, info *youtube_v2.PlaylistInfo, entries []string, opts downloader.DownloadOptions) error
+	IsDownloaded(id string) bool
+	IsInProgress(id string) bool
+}
+
+type Service struct {
+	Config        *config.Config
+	Downloader    Downloader
+	YoutubeClient youtube_v2.YoutubeClient
+
+	mu            sync.Mutex
+	path          string
+	Subscriptions map[string]*Subscription
+}
+
+func NewSubscriptionService(cfg *config.Config, downloader Downloader, youtubeClient youtube_v2.YoutubeClient) (*Service, error) {
+	s := &Service{
+		Config:        cfg,
+		Downloader:    downloader,
+		YoutubeClient: youtubeClient,
+		path:          filepath.Join(cfg.StateDir, "subscriptions.json"),
+		Subscriptions: make(map[string]*Subscription),
+	}
+	if err := filestore.Load(s.path, &s.Subscriptions); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Subscription is a watched playlist. Attempts counts how often each entry was queued without being downloaded,
+// entries that reach maxEntryAttempts are not queued again.
+type Subscription struct {
+	PlaylistID      string         `json:"playlist_id"`
+	IntervalMinutes int            `json:"interval_minutes"`
+	Paused          bool           `json:"paused"`
+	CreatedAt       time.Time      `json:"created_at"`
+	LastSyncAt      time.Time      `json:"last_sync_at,omitempty"`
+	LastSyncError   string         `json:"last_sync_error,omitempty"`
+	LastSyncQueued  int            `json:"last_sync_queued"`
+	Template        string         `json:"template,omitempty"`
+	PlaylistAsAlbum *bool          `json:"playlist_as_album,omitempty"`
+	KnownEntries    []string       `json:"known_entries"`
+	Attempts        map[string]int `json:"attempts,omitempty"`
+}
+
+// SubscriptionRequest is the body accepted when creating or updating a subscription. SkipExisting marks every
+// entry currently in the playlist as known so only tracks added from now on are downloaded.
+type SubscriptionRequest struct {
+	PlaylistID      string  `json:"playlist_id"`
+	IntervalMinutes int     `json:"interval_minutes"`
+	Paused          *bool   `json:"paused,omitempty"`
+	Template        *string `json:"template,omitempty"`
+	PlaylistAsAlbum *bool   `json:"playlist_as_album,omitempty"`
+	SkipExisting    bool    `json:"skip_existing,omitempty"`
+}
+
+const (
+	schedulerTick          = 30 * time.Second
+	defaultIntervalMinutes = 60
+	// maxEntryAttempts is how often an entry is queued before a subscription gives up on it
+	maxEntryAttempts = 3
+)
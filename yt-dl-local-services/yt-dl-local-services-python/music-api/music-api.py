@@ -54,7 +54,8 @@ class SimpleHTTPRequestHandler(http.server.SimpleHTTPRequestHandler):
                 'title': data['videoDetails']['title'],
                 'author': data['videoDetails']['author'],
                 'image': data['videoDetails']['thumbnail']['thumbnails'][-1]['url'],
-                'type': vtype
+                'type': vtype,
+                'duration': int(data['videoDetails'].get('lengthSeconds') or 0)
             }
             self.send_response(200)
             self.end_headers()
@@ -64,8 +65,20 @@ class SimpleHTTPRequestHandler(http.server.SimpleHTTPRequestHandler):
             tracks = ytmusic.get_playlist(playlistId=id, limit=None)
             vid = []
             for t in tracks["tracks"]:
-                vid.append({'id':t["videoId"]})
-            response = {'tracks': vid}
+                vid.append({
+                    'id': t["videoId"],
+                    'title': t.get("title") or "",
+                    'author': ", ".join(a["name"] for a in (t.get("artists") or [])),
+                    'duration': t.get("duration_seconds") or 0
+                })
+            author = tracks.get("author") or {}
+            thumbnails = tracks.get("thumbnails") or []
+            response = {
+                'title': tracks.get("title") or "",
+                'author': author.get("name", "") if isinstance(author, dict) else str(author),
+                'thumbnail': thumbnails[-1]["url"] if thumbnails else "",
+                'tracks': vid
+            }
             self.send_response(200)
             self.end_headers()
             self.wfile.write(json.dumps(response).encode('utf-8'))